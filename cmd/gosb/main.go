@@ -1,69 +1,501 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"go/build"
+	"go/token"
+	"io/fs"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
+	"github.com/slavaavr/go-struct-builder/internal/config"
 	"github.com/slavaavr/go-struct-builder/internal/labels"
 	"github.com/slavaavr/go-struct-builder/internal/model"
 	"github.com/slavaavr/go-struct-builder/internal/service"
 )
 
 var (
-	source   = flag.String("source", "", "[Required] Input Go source file")
-	features = flag.String("features", "", "[Optional] Comma separated list of features [ptr,arr,opt]")
+	source   = flag.String("source", "", "[Optional] Input Go source file, package patterns can be passed instead")
+	typ      = flag.String("type", "", "[Optional] Comma separated struct types of other packages, e.g. net/http.Server")
+	features = flag.String("features", "", "[Optional] Comma separated list of features [ptr,arr,opt,map]")
+	layout   = flag.String("layout", "file", "[Optional] Output file per source file or per package [file,package]")
+	mode     = flag.String("mode", "builder", "[Optional] Kind of generated code [builder,step,options]")
+	valid    = flag.String("validation", "all", "[Optional] Errors returned by building a struct [all,first]")
+	tmpl     = flag.String("template", "", "[Optional] Template file redefining the templates of the generated code")
+	check    = flag.Bool("check", false, "[Optional] Report stale output files with a diff instead of writing them")
+	outDir   = flag.String("output-dir", "", "[Optional] Directory the builders are written to instead of the sources one")
+	outPkg   = flag.String("output-pkg", "", "[Optional] Package name of the builders, the output dir one by default")
 )
 
+// output is a parsed file along with the path the generated builders must be written to.
+type output struct {
+	file     *model.File
+	path     string
+	settings *config.Settings
+}
+
 func main() {
 	flag.Parse()
 
-	if *source == "" {
-		log.Fatalf("source flag is not provided")
+	patterns := flag.Args()
+
+	if *typ != "" && (*source != "" || len(patterns) > 0) {
+		log.Fatalf("type flag can't be combined with source flag or package patterns")
+	}
+
+	// the package of the current directory, e.g. the one running go:generate, is generated by default
+	if *source == "" && *typ == "" && len(patterns) == 0 {
+		patterns = []string{"."}
 	}
 
-	features, err := labels.ParseFeatures(*features)
+	outputPkg, err := getOutputPackage()
 	if err != nil {
-		log.Fatalf("parsing features flag: %s", err)
+		log.Fatalf("%s", err)
+	}
+
+	var (
+		loader  = newSettingsLoader(getFlagsConfig())
+		outputs []output
+	)
+
+	// the output directory of the config is used unless it's set by the flag
+	outputPkg.Resolve = func(dir string) (service.OutputPackage, error) {
+		settings, err := loader.load(dir)
+		if err != nil || settings.OutputDir == "" {
+			return service.OutputPackage{Dir: "", Name: "", Resolve: nil}, err
+		}
+
+		return resolveOutputPackage(settings.OutputDir, *outPkg)
+	}
+
+	p := service.NewOutputParser(outputPkg)
+
+	switch {
+	case *typ != "":
+		outputs, err = parseTypes(p, loader, outputPkg.Dir, strings.Split(*typ, ","))
+
+	case *source != "":
+		outputs, err = parseSource(p, loader, outputPkg.Dir, *source)
+
+	default:
+		outputs, err = parsePackages(p, loader, outputPkg.Dir, patterns)
 	}
 
-	srcDir, err := filepath.Abs(filepath.Dir(*source))
 	if err != nil {
-		log.Fatalf("getting the source directory: %s", err)
+		log.Fatalf("%s", err)
+	}
+
+	if err = checkOutputPaths(outputs); err != nil {
+		log.Fatalf("%s", err)
 	}
 
-	filename := path.Join(srcDir, *source)
+	generated := make([]service.GeneratedFile, 0, len(outputs))
+
+	for _, out := range outputs {
+		customTemplate, err := readTemplate(out.settings.Template)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+
+		g := service.NewGenerator(out.settings.Generator, out.settings.Structs, customTemplate)
+
+		data, err := g.Generate(out.file)
+		if err != nil {
+			log.Fatalf("generating builder for the file='%s': %s", out.file.Name, err)
+		}
+
+		generated = append(generated, service.GeneratedFile{
+			Path: out.path,
+			Data: data,
+		})
+	}
+
+	// the outputs of different packages may be written into a single output package
+	if err = service.CheckGeneratedDecls(generated); err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	staleFiles := 0
+
+	for _, out := range generated {
+		if *check {
+			stale, err := checkOutput(out.Data, out.Path)
+			if err != nil {
+				log.Fatalf("checking output file: %s", err)
+			}
+
+			if stale {
+				staleFiles++
+			}
+
+			continue
+		}
+
+		if err = saveOutput(out.Data, out.Path); err != nil {
+			log.Fatalf("saving output file: %s", err)
+		}
+	}
+
+	if staleFiles > 0 {
+		log.Fatalf("%d output file(s) are stale, run gosb to regenerate them", staleFiles)
+	}
+}
+
+// getFlagsConfig returns the settings of the explicitly set flags, which take precedence over the config files.
+func getFlagsConfig() *config.Config {
+	res := &config.Config{
+		Features:   nil,
+		Mode:       "",
+		Validation: "",
+		Layout:     "",
+		Output:     "",
+		Template:   "",
+		Naming: config.NamingConfig{
+			Setter:        "",
+			FeatureSetter: "",
+			Builder:       "",
+			Constructor:   "",
+			Build:         "",
+			ToBuilder:     "",
+			Option:        "",
+		},
+		Structs: nil,
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "features":
+			res.Features = strings.Split(*features, ",")
+
+		case "mode":
+			res.Mode = *mode
+
+		case "validation":
+			res.Validation = *valid
+
+		case "layout":
+			res.Layout = *layout
+
+		case "template":
+			res.Template = *tmpl
+		}
+	})
+
+	return res
+}
+
+// settingsLoader loads the settings of the package directories merged with the flags.
+type settingsLoader struct {
+	flags    *config.Config
+	settings map[string]*config.Settings
+}
+
+func newSettingsLoader(flags *config.Config) *settingsLoader {
+	return &settingsLoader{
+		flags:    flags,
+		settings: make(map[string]*config.Settings),
+	}
+}
+
+func (l *settingsLoader) load(dir string) (*config.Settings, error) {
+	if res, ok := l.settings[dir]; ok {
+		return res, nil
+	}
+
+	cfg, err := config.Load(dir)
+	if err != nil {
+		return nil, fmt.Errorf("loading config of the directory='%s': %w", dir, err)
+	}
+
+	cfg.Override(l.flags)
+
+	res, err := cfg.Settings()
+	if err != nil {
+		return nil, fmt.Errorf("parsing config of the directory='%s': %w", dir, err)
+	}
+
+	l.settings[dir] = res
+
+	return res, nil
+}
+
+func parseSource(p service.Parser, loader *settingsLoader, outputDir, source string) ([]output, error) {
+	srcDir, err := filepath.Abs(filepath.Dir(source))
+	if err != nil {
+		return nil, fmt.Errorf("getting the source directory: %w", err)
+	}
+
+	filename := path.Join(srcDir, source)
 
 	file, err := os.Open(filename)
 	if err != nil {
-		log.Fatalf("openning the file='%s': %s", filename, err)
+		return nil, fmt.Errorf("openning the file='%s': %w", filename, err)
 	}
 
-	p := service.NewParser()
+	defer func() {
+		_ = file.Close()
+	}()
+
+	parsedFile, err := p.Parse(context.Background(), filename, file)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the file='%s': %w", filename, err)
+	}
 
-	parsedFile, err := p.Parse(file)
+	settings, err := loader.load(parsedFile.Path)
 	if err != nil {
-		log.Fatalf("parsing the file='%s': %s", filename, err)
+		return nil, err
 	}
 
-	g := service.NewGenerator(features)
+	return []output{
+		{
+			file:     parsedFile,
+			path:     path.Join(getOutputDir(outputDir, settings, parsedFile.Path), settings.GetOutputFileName(parsedFile.Name)),
+			settings: settings,
+		},
+	}, nil
+}
 
-	data, err := g.Generate(parsedFile)
+// parseTypes parses the struct types of other packages, the builders of which are written
+// to the output directory, the current one by default, e.g. server_builder.go for the net/http.Server.
+func parseTypes(p service.Parser, loader *settingsLoader, outputDir string, typeNames []string) ([]output, error) {
+	settings, err := loader.load(".")
 	if err != nil {
-		log.Fatalf("generating builder: %s", err)
+		return nil, err
 	}
 
-	if err = saveOutput(data, parsedFile); err != nil {
-		log.Fatalf("saving output file: %s", err)
+	dir := getOutputDir(outputDir, settings, ".")
+
+	res := make([]output, 0, len(typeNames))
+
+	for _, typeName := range typeNames {
+		typeName = strings.TrimSpace(typeName)
+
+		parsedFile, err := p.ParseType(context.Background(), dir, typeName)
+		if err != nil {
+			return nil, fmt.Errorf("parsing the type='%s': %w", typeName, err)
+		}
+
+		// the settings of the output directory set by the flag are used the same way as the ones of the current one
+		if outputDir != "" {
+			if settings, err = loader.load(parsedFile.Path); err != nil {
+				return nil, err
+			}
+		}
+
+		name := strings.ToLower(parsedFile.Structs[0].Name)
+
+		res = append(res, output{
+			file:     parsedFile,
+			path:     path.Join(parsedFile.Path, settings.GetOutputFileName(name)),
+			settings: settings,
+		})
 	}
+
+	return res, nil
 }
 
-func saveOutput(data []byte, f *model.File) error {
-	outputFile := path.Join(f.Path, getOutputFileName(f.Name))
+func parsePackages(p service.Parser, loader *settingsLoader, outputDir string, patterns []string) ([]output, error) {
+	files, err := p.ParsePackages(context.Background(), "", patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing packages %v: %w", patterns, err)
+	}
+
+	var (
+		res      = make([]output, 0, len(files))
+		pkgDirs  = make([]string, 0)
+		pkgFiles = make(map[string][]*model.File)
+	)
+
+	for _, f := range files {
+		if _, ok := pkgFiles[f.Path]; !ok {
+			pkgDirs = append(pkgDirs, f.Path)
+		}
+
+		pkgFiles[f.Path] = append(pkgFiles[f.Path], f)
+	}
+
+	for _, dir := range pkgDirs {
+		settings, err := loader.load(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if settings.Layout == labels.LayoutFile {
+			for _, f := range pkgFiles[dir] {
+				res = append(res, output{
+					file:     f,
+					path:     path.Join(getOutputDir(outputDir, settings, dir), settings.GetOutputFileName(f.Name)),
+					settings: settings,
+				})
+			}
+
+			continue
+		}
+
+		merged, err := service.MergeFiles(pkgFiles[dir])
+		if err != nil {
+			return nil, fmt.Errorf("merging files of the package='%s': %w", dir, err)
+		}
+
+		res = append(res, output{
+			file:     merged,
+			path:     path.Join(getOutputDir(outputDir, settings, dir), settings.GetOutputFileName(merged.Pkg)),
+			settings: settings,
+		})
+	}
+
+	return res, nil
+}
+
+// getOutputPackage returns the package set by the output flags, the absolute path of the output directory
+// is empty if the builders are written next to the sources.
+func getOutputPackage() (service.OutputPackage, error) {
+	res := service.OutputPackage{
+		Dir:     "",
+		Name:    *outPkg,
+		Resolve: nil,
+	}
+
+	if *outPkg != "" && !token.IsIdentifier(*outPkg) {
+		return res, fmt.Errorf("output package name='%s' is not an identifier", *outPkg)
+	}
+
+	if *outDir == "" {
+		if *outPkg != "" && !isTestPackage(*outPkg) {
+			return res, errors.New("output package can't be set without the output directory, " +
+				"except an external test package")
+		}
+
+		return res, nil
+	}
+
+	return resolveOutputPackage(*outDir, *outPkg)
+}
+
+// resolveOutputPackage returns the package of the output directory, the name of which is the one
+// of the Go files in the directory by default.
+func resolveOutputPackage(outputDir, name string) (service.OutputPackage, error) {
+	res := service.OutputPackage{
+		Dir:     "",
+		Name:    name,
+		Resolve: nil,
+	}
+
+	dir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return res, fmt.Errorf("getting absolute path of the output directory='%s': %w", outputDir, err)
+	}
+
+	res.Dir = dir
+
+	existingName, err := getPackageName(dir)
+	if err != nil {
+		return res, err
+	}
+
+	if existingName == "" {
+		return res, nil
+	}
+
+	if res.Name != "" && res.Name != existingName && res.Name != existingName+testPackageSuffix {
+		return res, fmt.Errorf("output package name='%s' differs from the package='%s' of the output directory='%s'",
+			res.Name, existingName, dir)
+	}
+
+	if res.Name == "" {
+		res.Name = existingName
+	}
+
+	return res, nil
+}
+
+// testPackageSuffix is the suffix of an external test package name, which shares the directory with the package.
+const testPackageSuffix = "_test"
+
+func isTestPackage(name string) bool {
+	return strings.HasSuffix(name, testPackageSuffix)
+}
+
+// getPackageName returns the name of the package declared in the dir, empty if there is no Go files.
+func getPackageName(dir string) (string, error) {
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		var noGoErr *build.NoGoError
+		if errors.As(err, &noGoErr) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("reading package of the output directory='%s': %w", dir, err)
+	}
+
+	return pkg.Name, nil
+}
+
+// getOutputDir returns the directory the builders of the sources in the dir are written to,
+// the output directory flag takes precedence over the one of the config.
+func getOutputDir(outputDir string, settings *config.Settings, dir string) string {
+	switch {
+	case outputDir != "":
+		return outputDir
+
+	case settings.OutputDir != "":
+		return settings.OutputDir
+
+	default:
+		return dir
+	}
+}
+
+// checkOutputPaths reports the outputs written to the same file, e.g. the builders of the x.go files
+// of different packages written to a single output directory, and the test package outputs of non-test files.
+func checkOutputPaths(outputs []output) error {
+	paths := make(map[string]struct{}, len(outputs))
+
+	for _, out := range outputs {
+		if _, ok := paths[out.path]; ok {
+			return fmt.Errorf("output file='%s' is generated more than once, use the package layout "+
+				"or the output file name pattern", out.path)
+		}
+
+		if isTestPackage(out.file.Pkg) && !strings.HasSuffix(out.path, "_test.go") {
+			return fmt.Errorf("output file='%s' of the external test package='%s' must end with _test.go, "+
+				"use the output file name pattern", out.path, out.file.Pkg)
+		}
+
+		paths[out.path] = struct{}{}
+	}
+
+	return nil
+}
+
+// readTemplate returns the content of the custom template file, empty if no file is provided.
+func readTemplate(filename string) (string, error) {
+	if filename == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("reading template file='%s': %w", filename, err)
+	}
+
+	return string(data), nil
+}
+
+func saveOutput(data []byte, outputFile string) error {
+	if err := os.MkdirAll(filepath.Dir(outputFile), os.ModePerm); err != nil {
+		return fmt.Errorf("creating output directory='%s': %w", filepath.Dir(outputFile), err)
+	}
 
 	if err := os.WriteFile(outputFile, data, os.ModePerm); err != nil {
 		return fmt.Errorf("writing to output file='%s': %w", outputFile, err)
@@ -72,6 +504,19 @@ func saveOutput(data []byte, f *model.File) error {
 	return nil
 }
 
-func getOutputFileName(file string) string {
-	return fmt.Sprintf("%s_builder.go", strings.TrimSuffix(file, ".go"))
+func checkOutput(data []byte, outputFile string) (bool, error) {
+	diff, err := service.CheckOutput(outputFile, data)
+	if err != nil {
+		return false, err
+	}
+
+	if diff == "" {
+		return false, nil
+	}
+
+	if _, err = fmt.Fprint(os.Stdout, diff); err != nil {
+		return false, fmt.Errorf("printing diff: %w", err)
+	}
+
+	return true, nil
 }
@@ -0,0 +1,201 @@
+// Package gosb generates builders of Go structs the same way the gosb command does,
+// keeping both the sources and the generated files in memory.
+package gosb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/slavaavr/go-struct-builder/internal/config"
+	"github.com/slavaavr/go-struct-builder/internal/labels"
+	"github.com/slavaavr/go-struct-builder/internal/model"
+	"github.com/slavaavr/go-struct-builder/internal/service"
+)
+
+// DefaultTemplate is the template the builders are generated from, its templates can be redefined
+// by the Template option.
+var DefaultTemplate = service.DefaultTemplate
+
+// The data the templates of the DefaultTemplate are executed with.
+type (
+	TemplateData       = service.TemplateData
+	StructData         = service.StructData
+	FieldData          = service.FieldData
+	SetterData         = service.SetterData
+	MapData            = service.MapData
+	NestedData         = service.NestedData
+	ValidationData     = service.ValidationData
+	RegexpData         = service.RegexpData
+	StepData           = service.StepData
+	FieldTemplateData  = service.FieldTemplateData
+	SetterTemplateData = service.SetterTemplateData
+	StepTemplateData   = service.StepTemplateData
+	ErrTemplateData    = service.ErrTemplateData
+)
+
+// Naming define the names of the builders by text/template patterns executed with the NamingData,
+// empty patterns stand for the default ones, e.g. Set{{.Field}} for the Setter.
+type (
+	Naming     = service.Naming
+	NamingData = service.NamingData
+)
+
+// Options define the sources of a single package and the code generated for them.
+type Options struct {
+	// Dir is the directory the sources belong to, their imports are resolved within its module.
+	// The current directory is used by default.
+	Dir string
+	// Sources are Go source files keyed by the file names
+	Sources map[string][]byte
+	// FS is a file system with Go source files in its root, it's used when no Sources are provided
+	FS fs.FS
+
+	// Features, Mode, Validation and Layout have the same values as the flags of the gosb command
+	Features   []string
+	Mode       string
+	Validation string
+	Layout     string
+	// Output is the output file name pattern, e.g. {name}_builder.go
+	Output string
+	// Template is the text of a custom template redefining the templates of the DefaultTemplate
+	Template string
+	// Naming are the naming patterns of the builders, e.g. Naming{Setter: "With{{.Field}}"}
+	Naming Naming
+	// Structs override the settings for the structs with the given name
+	Structs map[string]StructOptions
+}
+
+// StructOptions override the settings for a struct, unset values are inherited from the Options,
+// e.g. nil Features are inherited while empty ones turn off all features.
+type StructOptions struct {
+	Features   []string
+	Mode       string
+	Validation string
+	Naming     Naming
+}
+
+// File is a generated file.
+type File struct {
+	// Name is the name of the file in the directory of the sources, e.g. user_builder.go
+	Name string
+	// Source is the model the file is generated from
+	Source *Source
+	// Content is the formatted Go code
+	Content []byte
+}
+
+// Generate parses the sources and generates builders of the annotated structs.
+func Generate(ctx context.Context, opts Options) ([]File, error) {
+	sources, err := Parse(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return Render(opts, sources)
+}
+
+// Parse returns the models of the source files having annotated structs,
+// they can be modified before rendering.
+func Parse(ctx context.Context, opts Options) ([]*Source, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	switch {
+	case len(opts.Sources) > 0:
+		return service.NewParser().ParseSources(ctx, dir, opts.Sources)
+
+	case opts.FS != nil:
+		return service.NewParser().ParseFS(ctx, dir, opts.FS)
+
+	default:
+		return nil, errors.New("neither sources nor file system are provided")
+	}
+}
+
+// Render generates builders of the models.
+func Render(opts Options, sources []*Source) ([]File, error) {
+	settings, err := opts.config().Settings()
+	if err != nil {
+		return nil, fmt.Errorf("parsing options: %w", err)
+	}
+
+	if settings.OutputDir != "" {
+		return nil, fmt.Errorf("output='%s' must be a name of a Go file, the files are written by the caller",
+			opts.Output)
+	}
+
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	if settings.Layout == labels.LayoutPackage {
+		merged, err := service.MergeFiles(sources)
+		if err != nil {
+			return nil, fmt.Errorf("merging sources: %w", err)
+		}
+
+		sources = []*model.File{merged}
+	}
+
+	res := make([]File, 0, len(sources))
+
+	for _, src := range sources {
+		data, err := service.NewGenerator(settings.Generator, settings.Structs, opts.Template).Generate(src)
+		if err != nil {
+			return nil, fmt.Errorf("generating builder for the file='%s': %w", src.Name, err)
+		}
+
+		name := src.Name
+		if settings.Layout == labels.LayoutPackage {
+			name = src.Pkg
+		}
+
+		res = append(res, File{
+			Name:    settings.GetOutputFileName(name),
+			Source:  src,
+			Content: data,
+		})
+	}
+
+	return res, nil
+}
+
+func (o Options) config() *config.Config {
+	structs := make(map[string]config.StructConfig, len(o.Structs))
+
+	for name, st := range o.Structs {
+		structs[name] = config.StructConfig{
+			Features:   st.Features,
+			Mode:       st.Mode,
+			Validation: st.Validation,
+			Naming:     getNamingConfig(st.Naming),
+		}
+	}
+
+	return &config.Config{
+		Features:   o.Features,
+		Mode:       o.Mode,
+		Validation: o.Validation,
+		Layout:     o.Layout,
+		Output:     o.Output,
+		Template:   "",
+		Naming:     getNamingConfig(o.Naming),
+		Structs:    structs,
+	}
+}
+
+func getNamingConfig(n Naming) config.NamingConfig {
+	return config.NamingConfig{
+		Setter:        n.Setter,
+		FeatureSetter: n.FeatureSetter,
+		Builder:       n.Builder,
+		Constructor:   n.Constructor,
+		Build:         n.Build,
+		ToBuilder:     n.ToBuilder,
+		Option:        n.Option,
+	}
+}
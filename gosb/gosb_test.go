@@ -0,0 +1,410 @@
+package gosb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const source = `
+package main
+
+//go:generate gosb
+type A struct {
+	F1 int
+}
+
+//go:generate gosb
+type B struct {
+	F2 *int
+}`
+
+func TestGenerate(t *testing.T) {
+	files, err := Generate(context.Background(), Options{
+		Dir:        t.TempDir(),
+		Sources:    map[string][]byte{"a.go": []byte(source)},
+		FS:         nil,
+		Features:   []string{"ptr"},
+		Mode:       "",
+		Validation: "",
+		Layout:     "",
+		Output:     "",
+		Template:   "",
+		Naming: Naming{
+			Setter:        "",
+			FeatureSetter: "",
+			Builder:       "",
+			Constructor:   "",
+			Build:         "Create",
+			ToBuilder:     "",
+			Option:        "",
+		},
+		Structs: map[string]StructOptions{"B": {Features: []string{}, Mode: "options"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	assert.Equal(t, "a_builder.go", files[0].Name)
+	assert.Equal(t, "a.go", files[0].Source.Name)
+	assert.Contains(t, string(files[0].Content), "func NewABuilder() *ABuilder {")
+	assert.Contains(t, string(files[0].Content), "func (b *ABuilder) Create() (*A, error) {")
+	assert.Contains(t, string(files[0].Content), "func NewB(opts ...BOption) (*B, error) {")
+	assert.Contains(t, string(files[0].Content), "func WithF2(v *int) BOption {")
+	assert.NotContains(t, string(files[0].Content), "WithF2V")
+}
+
+func TestGenerate_FS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.go":      {Data: []byte(source)},
+		"a_test.go": {Data: []byte("package main\n\nfunc f() {")},
+		"b.go":      {Data: []byte("package main\n\n//go:generate gosb\ntype C struct {\n\tF3 int\n}")},
+	}
+
+	files, err := Generate(context.Background(), Options{
+		Dir:        t.TempDir(),
+		Sources:    nil,
+		FS:         fsys,
+		Features:   nil,
+		Mode:       "",
+		Validation: "",
+		Layout:     "package",
+		Output:     "{name}_gen.go",
+		Template:   "",
+		Naming:     Naming{},
+		Structs:    nil,
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	assert.Equal(t, "main_gen.go", files[0].Name)
+	assert.Equal(t, []string{"A", "B", "C"}, getStructNames(files[0].Source))
+}
+
+// fromSource has the structs the builders of which are seeded from a struct value, except the ones
+// containing a lock, which can't be copied.
+const fromSource = `
+package main
+
+import "sync"
+
+//go:generate gosb
+type A struct {
+	F1 []int          ` + "`gosb:\"optional\"`" + `
+	F2 map[string]int ` + "`gosb:\"optional\"`" + `
+	F3 int            ` + "`gosb:\"optional\"`" + `
+}
+
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
+
+//go:generate gosb
+type B struct {
+	mu sync.Mutex ` + "`gosb:\"-\"`" + `
+	F1 int        ` + "`gosb:\"optional\"`" + `
+}
+
+//go:generate gosb
+type C struct {
+	_  [1]noCopy
+	F1 int ` + "`gosb:\"optional\"`" + `
+}`
+
+// fromMain checks the builders seeded from a nil value and from a struct value detached from it.
+const fromMain = `
+package main
+
+func main() {
+	if a := NewABuilderFrom(nil).Build(); a.F1 != nil || a.F2 != nil || a.F3 != 0 {
+		panic("nil value isn't an empty builder")
+	}
+
+	src := &A{F1: make([]int, 1, 2), F2: map[string]int{"a": 1}, F3: 1}
+
+	a := src.ToBuilder().SetF1V(2).PutF2("b", 2).SetF3(3).Build()
+	if len(a.F1) != 2 || len(a.F2) != 2 || a.F3 != 3 {
+		panic("builder isn't seeded from the struct value")
+	}
+
+	if src.F1[:2][1] != 0 || len(src.F2) != 1 || src.F3 != 1 {
+		panic("struct value is changed by the builder")
+	}
+
+	_ = NewBBuilder().SetF1(1).Build()
+	_ = NewCBuilder().SetF1(1).Build()
+}`
+
+func TestGenerate_From(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building the generated code is skipped in the short mode")
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command isn't found")
+	}
+
+	gosberrDir, err := filepath.Abs(filepath.Join("..", "gosberr"))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	goMod := "module example.com/from\n\ngo 1.22\n\n" +
+		"require github.com/slavaavr/go-struct-builder/gosberr v0.0.0\n\n" +
+		"replace github.com/slavaavr/go-struct-builder/gosberr => " + gosberrDir + "\n"
+
+	files, err := Generate(context.Background(), Options{
+		Dir:        dir,
+		Sources:    map[string][]byte{"a.go": []byte(fromSource)},
+		FS:         nil,
+		Features:   []string{"arr", "map"},
+		Mode:       "",
+		Validation: "",
+		Layout:     "",
+		Output:     "",
+		Template:   "",
+		Naming:     Naming{},
+		Structs:    nil,
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.NotContains(t, content, "NewBBuilderFrom")
+	assert.NotContains(t, content, "func (t *B) ToBuilder()")
+	assert.NotContains(t, content, "NewCBuilderFrom")
+
+	for name, data := range map[string]string{
+		"go.mod":      goMod,
+		"a.go":        fromSource,
+		"main.go":     fromMain,
+		files[0].Name: content,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600))
+	}
+
+	for _, args := range [][]string{{"vet", "."}, {"run", "."}} {
+		cmd := exec.Command(goBin, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "go %s: %s", args[0], out)
+	}
+}
+
+func TestGenerate_PackageNames(t *testing.T) {
+	cases := []struct {
+		name        string
+		sources     map[string]string
+		expectedErr string
+	}{
+		{
+			name: "options of different files",
+			sources: map[string]string{
+				"a.go": "package main\n\n//gosb:builder mode=options option=With{{.Field}}\ntype A struct {\n\tF1 int\n}",
+				"b.go": "package main\n\n//gosb:builder mode=options option=With{{.Field}}\ntype B struct {\n\tF1 int\n}",
+			},
+			expectedErr: "generating builder for the file='a.go': " +
+				"option function='WithF1' of the struct='A' is already generated for the struct='B'",
+		},
+		{
+			name: "builders of different files",
+			sources: map[string]string{
+				"a.go": "package main\n\n//gosb:builder builder=Factory\ntype A struct {\n\tF1 int\n}",
+				"b.go": "package main\n\n//gosb:builder builder=Factory\ntype B struct {\n\tF1 int\n}",
+			},
+			expectedErr: "generating builder for the file='a.go': " +
+				"name='Factory' of the struct='A' is already used by the struct='B'",
+		},
+		{
+			name: "declarations of the package",
+			sources: map[string]string{
+				"a.go": "package main\n\n//gosb:builder builder={{.Struct}}Factory\ntype A struct {\n\tF1 int\n}",
+				"b.go": "package main\n\ntype AFactory struct{}",
+			},
+			expectedErr: "generating builder for the file='a.go': " +
+				"name='AFactory' of the struct='A' is already declared by the package",
+		},
+		{
+			name: "declarations of the generated files",
+			sources: map[string]string{
+				"a.go":         "package main\n\n//go:generate gosb\ntype A struct {\n\tF1 int\n}",
+				"a_builder.go": "// Code generated by go-struct-builder. DO NOT EDIT.\n\npackage main\n\ntype ABuilder struct{}",
+			},
+			expectedErr: "",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			sources := make(map[string][]byte, len(c.sources))
+			for name, src := range c.sources {
+				sources[name] = []byte(src)
+			}
+
+			_, err := Generate(context.Background(), Options{
+				Dir:        t.TempDir(),
+				Sources:    sources,
+				FS:         nil,
+				Features:   nil,
+				Mode:       "",
+				Validation: "",
+				Layout:     "",
+				Output:     "",
+				Template:   "",
+				Naming:     Naming{},
+				Structs:    nil,
+			})
+			if c.expectedErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.EqualError(t, err, c.expectedErr)
+		})
+	}
+}
+
+func TestGenerate_CustomHeader(t *testing.T) {
+	opts := Options{
+		Dir:        t.TempDir(),
+		Sources:    map[string][]byte{"a.go": []byte(source)},
+		FS:         nil,
+		Features:   nil,
+		Mode:       "",
+		Validation: "",
+		Layout:     "",
+		Output:     "",
+		Template:   "{{define \"header\"}}// Code generated by our tool. DO NOT EDIT.\n\npackage {{.File.Pkg}}\n{{end}}",
+		Naming:     Naming{},
+		Structs:    nil,
+	}
+
+	first, err := Generate(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+
+	// the builders are regenerated along with the output of the previous run
+	opts.Sources[first[0].Name] = first[0].Content
+
+	second, err := Generate(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+
+	assert.Equal(t, first[0].Content, second[0].Content)
+}
+
+func TestParse_Render(t *testing.T) {
+	opts := Options{
+		Dir:        t.TempDir(),
+		Sources:    map[string][]byte{"a.go": []byte(source)},
+		FS:         nil,
+		Features:   nil,
+		Mode:       "",
+		Validation: "",
+		Layout:     "",
+		Output:     "",
+		Template:   "",
+		Naming:     Naming{},
+		Structs:    nil,
+	}
+
+	sources, err := Parse(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+
+	// the model can be modified before rendering
+	sources[0].Structs = sources[0].Structs[:1]
+	sources[0].Structs[0].Fields[0].Required = false
+
+	files, err := Render(opts, sources)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	assert.Contains(t, string(files[0].Content), "func (b *ABuilder) Build() *A {")
+	assert.NotContains(t, string(files[0].Content), "BBuilder")
+}
+
+func TestRender_Twice(t *testing.T) {
+	opts := Options{
+		Dir:        t.TempDir(),
+		Sources:    map[string][]byte{"a.go": []byte(source)},
+		FS:         nil,
+		Features:   nil,
+		Mode:       "",
+		Validation: "",
+		Layout:     "",
+		Output:     "",
+		Template:   "",
+		Naming:     Naming{},
+		Structs:    nil,
+	}
+
+	sources, err := Parse(context.Background(), opts)
+	require.NoError(t, err)
+
+	first, err := Render(opts, sources)
+	require.NoError(t, err)
+
+	second, err := Render(opts, sources)
+	require.NoError(t, err)
+
+	// the imports of the generated code aren't added to the sources
+	assert.Empty(t, sources[0].Imports)
+	assert.Equal(t, first, second)
+}
+
+func TestParse_NoSources(t *testing.T) {
+	_, err := Parse(context.Background(), Options{
+		Dir:        "",
+		Sources:    nil,
+		FS:         nil,
+		Features:   nil,
+		Mode:       "",
+		Validation: "",
+		Layout:     "",
+		Output:     "",
+		Template:   "",
+		Naming:     Naming{},
+		Structs:    nil,
+	})
+	require.Equal(t, errors.New("neither sources nor file system are provided"), err)
+}
+
+func TestRender_OutputDir(t *testing.T) {
+	_, err := Render(Options{
+		Dir:        "",
+		Sources:    nil,
+		FS:         nil,
+		Features:   nil,
+		Mode:       "",
+		Validation: "",
+		Layout:     "",
+		Output:     "builders/{name}_builder.go",
+		Template:   "",
+		Naming:     Naming{},
+		Structs:    nil,
+	}, nil)
+	require.EqualError(t, err, "output='builders/{name}_builder.go' must be a name of a Go file, "+
+		"the files are written by the caller")
+}
+
+func getStructNames(src *Source) []string {
+	res := make([]string, 0, len(src.Structs))
+
+	for _, st := range src.Structs {
+		res = append(res, st.Name)
+	}
+
+	return res
+}
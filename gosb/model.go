@@ -0,0 +1,41 @@
+package gosb
+
+import (
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+// The parsed model of the sources, see the Parse function.
+type (
+	// Source is a parsed source file along with its annotated structs.
+	Source     = model.File
+	Import     = model.Import
+	Struct     = model.Struct
+	TypeParam  = model.TypeParam
+	Field      = model.Field
+	FieldType  = model.FieldType
+	Nested     = model.Nested
+	Validation = model.Validation
+	TypeInfo   = model.TypeInfo
+	TypeKind   = model.TypeKind
+)
+
+const (
+	TypeInfoOther   = model.TypeInfoOther
+	TypeInfoArray   = model.TypeInfoArray
+	TypeInfoPointer = model.TypeInfoPointer
+	TypeInfoOption  = model.TypeInfoOption
+	TypeInfoMap     = model.TypeInfoMap
+)
+
+const (
+	TypeKindOther    = model.TypeKindOther
+	TypeKindBool     = model.TypeKindBool
+	TypeKindInt      = model.TypeKindInt
+	TypeKindFloat    = model.TypeKindFloat
+	TypeKindString   = model.TypeKindString
+	TypeKindSlice    = model.TypeKindSlice
+	TypeKindMap      = model.TypeKindMap
+	TypeKindArray    = model.TypeKindArray
+	TypeKindStruct   = model.TypeKindStruct
+	TypeKindNillable = model.TypeKindNillable
+)
@@ -0,0 +1,80 @@
+// Package gosberr provides errors returned by the builders generated by go-struct-builder.
+package gosberr
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMissingField matches every MissingFieldError with errors.Is.
+	ErrMissingField = errors.New("field is not provided")
+	// ErrInvalidField matches every InvalidFieldError with errors.Is.
+	ErrInvalidField = errors.New("field is invalid")
+)
+
+// MissingFieldError is returned when a required field was not provided to a builder.
+type MissingFieldError struct {
+	Struct string
+	// Field is the name of the field, or a path of the field of a nested struct, e.g. Addr.City
+	Field string
+}
+
+func (e *MissingFieldError) Error() string {
+	return fmt.Sprintf("%s.%s field is not provided", e.Struct, e.Field)
+}
+
+func (e *MissingFieldError) Is(target error) bool {
+	return target == ErrMissingField
+}
+
+// InvalidFieldError is returned when a field value violates a validation rule, e.g. min=1.
+type InvalidFieldError struct {
+	Struct string
+	// Field is the name of the field, or a path of the field of a nested struct, e.g. Addr.City
+	Field string
+	Rule  string
+	// Msg describes the violated rule, e.g. "must be greater than or equal to 1"
+	Msg string
+}
+
+func (e *InvalidFieldError) Error() string {
+	return fmt.Sprintf("%s.%s field %s", e.Struct, e.Field, e.Msg)
+}
+
+func (e *InvalidFieldError) Is(target error) bool {
+	return target == ErrInvalidField
+}
+
+// Nested prefixes the fields of the errors returned by a nested builder with the path of the field
+// the nested struct was built for, e.g. the City field of the struct built for the A.Addr field becomes A.Addr.City.
+func Nested(structName, fieldName string, err error) error {
+	switch e := err.(type) { // nolint: errorlint
+	case *MissingFieldError:
+		return &MissingFieldError{
+			Struct: structName,
+			Field:  fieldName + "." + e.Field,
+		}
+
+	case *InvalidFieldError:
+		return &InvalidFieldError{
+			Struct: structName,
+			Field:  fieldName + "." + e.Field,
+			Rule:   e.Rule,
+			Msg:    e.Msg,
+		}
+
+	case interface{ Unwrap() []error }:
+		errs := e.Unwrap()
+		res := make([]error, 0, len(errs))
+
+		for _, err := range errs {
+			res = append(res, Nested(structName, fieldName, err))
+		}
+
+		return errors.Join(res...)
+
+	default:
+		return fmt.Errorf("%s.%s: %w", structName, fieldName, err)
+	}
+}
@@ -0,0 +1,46 @@
+package gosberr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrors(t *testing.T) {
+	err := errors.Join(
+		&MissingFieldError{Struct: "A", Field: "F1"},
+		&InvalidFieldError{Struct: "A", Field: "F2", Rule: "min", Msg: "must be greater than or equal to 1"},
+	)
+
+	assert.Equal(t, "A.F1 field is not provided\nA.F2 field must be greater than or equal to 1", err.Error())
+	assert.ErrorIs(t, err, ErrMissingField)
+	assert.ErrorIs(t, err, ErrInvalidField)
+
+	var missingErr *MissingFieldError
+	require.ErrorAs(t, err, &missingErr)
+	assert.Equal(t, &MissingFieldError{Struct: "A", Field: "F1"}, missingErr)
+
+	var invalidErr *InvalidFieldError
+	require.ErrorAs(t, err, &invalidErr)
+	assert.Equal(t, "min", invalidErr.Rule)
+
+	assert.NotErrorIs(t, &MissingFieldError{Struct: "A", Field: "F1"}, ErrInvalidField)
+}
+
+func TestNested(t *testing.T) {
+	err := Nested("A", "Addr", errors.Join(
+		&MissingFieldError{Struct: "Address", Field: "City"},
+		Nested("Address", "Geo", &InvalidFieldError{Struct: "Geo", Field: "Lat", Rule: "max", Msg: "must be less than or equal to 90"}),
+		errors.New("unexpected"),
+	))
+
+	assert.Equal(t, "A.Addr.City field is not provided\n"+
+		"A.Addr.Geo.Lat field must be less than or equal to 90\n"+
+		"A.Addr: unexpected", err.Error())
+
+	var invalidErr *InvalidFieldError
+	require.ErrorAs(t, err, &invalidErr)
+	assert.Equal(t, &InvalidFieldError{Struct: "A", Field: "Addr.Geo.Lat", Rule: "max", Msg: "must be less than or equal to 90"}, invalidErr)
+}
@@ -0,0 +1,369 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/slavaavr/go-struct-builder/internal/labels"
+	"github.com/slavaavr/go-struct-builder/internal/service"
+)
+
+const (
+	// FileName is the name of the config files looked up from the source directory up to the module root.
+	FileName = "gosb.yaml"
+
+	goModFileName = "go.mod"
+
+	// OutputNamePlaceholder stands for the source file name without extension, or the package name
+	// for the package layout, in the output file name pattern
+	OutputNamePlaceholder = "{name}"
+	defaultOutput         = OutputNamePlaceholder + "_builder.go"
+)
+
+// Config is the content of a config file, unset values are empty.
+type Config struct {
+	Features []string `yaml:"features"`
+	Mode     string   `yaml:"mode"`
+	// Validation is the validation style, all or first
+	Validation string `yaml:"validation"`
+	Layout     string `yaml:"layout"`
+	// Output is the output file name pattern, e.g. {name}_builder.go, along with an optional directory
+	// relative to the config file, e.g. ../builders/{name}_builder.go, or the directory only, e.g. ../builders/
+	Output string `yaml:"output"`
+	// Template is the path of the custom template file, relative to the config file
+	Template string                  `yaml:"template"`
+	Naming   NamingConfig            `yaml:"naming"`
+	Structs  map[string]StructConfig `yaml:"structs"`
+}
+
+// StructConfig overrides the settings for the structs with the given name.
+type StructConfig struct {
+	Features   []string     `yaml:"features"`
+	Mode       string       `yaml:"mode"`
+	Validation string       `yaml:"validation"`
+	Naming     NamingConfig `yaml:"naming"`
+}
+
+// NamingConfig are the naming patterns of the generated code, see service.Naming.
+type NamingConfig struct {
+	Setter        string `yaml:"setter"`
+	FeatureSetter string `yaml:"feature_setter"`
+	Builder       string `yaml:"builder"`
+	Constructor   string `yaml:"constructor"`
+	Build         string `yaml:"build"`
+	ToBuilder     string `yaml:"to_builder"`
+	Option        string `yaml:"option"`
+}
+
+// Settings are the parsed settings of a config.
+type Settings struct {
+	Generator service.Settings
+	Structs   map[string]service.Settings
+	Layout    labels.Layout
+	// Output is the output file name pattern, OutputDir is the absolute directory of the output files,
+	// empty for the directory of the sources
+	Output    string
+	OutputDir string
+	// Template is the path of the custom template file, empty for the default template
+	Template string
+}
+
+// Load reads and merges the config files found walking up from the directory to the module root,
+// the closer to the directory a file is, the higher precedence its settings have.
+func Load(dir string) (*Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute path of the directory='%s': %w", dir, err)
+	}
+
+	var files []string
+
+	for {
+		filename := filepath.Join(dir, FileName)
+
+		if _, err = os.Stat(filename); err == nil {
+			files = append(files, filename)
+		}
+
+		parent := filepath.Dir(dir)
+
+		if _, err = os.Stat(filepath.Join(dir, goModFileName)); err == nil || parent == dir {
+			break
+		}
+
+		dir = parent
+	}
+
+	res := &Config{
+		Features:   nil,
+		Mode:       "",
+		Validation: "",
+		Layout:     "",
+		Output:     "",
+		Template:   "",
+		Naming: NamingConfig{
+			Setter:        "",
+			FeatureSetter: "",
+			Builder:       "",
+			Constructor:   "",
+			Build:         "",
+			ToBuilder:     "",
+			Option:        "",
+		},
+		Structs: nil,
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		cfg, err := readFile(files[i])
+		if err != nil {
+			return nil, err
+		}
+
+		res.Merge(cfg)
+	}
+
+	return res, nil
+}
+
+func readFile(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file='%s': %w", filename, err)
+	}
+
+	var cfg Config
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err = dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing config file='%s': %w", filename, err)
+	}
+
+	if cfg.Template != "" && !filepath.IsAbs(cfg.Template) {
+		cfg.Template = filepath.Join(filepath.Dir(filename), cfg.Template)
+	}
+
+	// the output directory is kept along with the trailing separator of a directory without a file name
+	if dir, name := filepath.Split(cfg.Output); dir != "" && !filepath.IsAbs(dir) {
+		cfg.Output = filepath.Join(filepath.Dir(filename), dir) + string(filepath.Separator) + name
+	}
+
+	return &cfg, nil
+}
+
+// Merge overrides the settings with the ones set in the other config.
+func (c *Config) Merge(other *Config) {
+	if other.Features != nil {
+		c.Features = other.Features
+	}
+
+	if other.Mode != "" {
+		c.Mode = other.Mode
+	}
+
+	if other.Validation != "" {
+		c.Validation = other.Validation
+	}
+
+	if other.Layout != "" {
+		c.Layout = other.Layout
+	}
+
+	if other.Output != "" {
+		c.Output = other.Output
+	}
+
+	if other.Template != "" {
+		c.Template = other.Template
+	}
+
+	c.Naming.Merge(other.Naming)
+
+	for name, st := range other.Structs {
+		if c.Structs == nil {
+			c.Structs = make(map[string]StructConfig)
+		}
+
+		res := c.Structs[name]
+
+		if st.Features != nil {
+			res.Features = st.Features
+		}
+
+		if st.Mode != "" {
+			res.Mode = st.Mode
+		}
+
+		if st.Validation != "" {
+			res.Validation = st.Validation
+		}
+
+		res.Naming.Merge(st.Naming)
+
+		c.Structs[name] = res
+	}
+}
+
+// Merge overrides the naming patterns with the ones set in the other config.
+func (n *NamingConfig) Merge(other NamingConfig) {
+	if other.Setter != "" {
+		n.Setter = other.Setter
+	}
+
+	if other.FeatureSetter != "" {
+		n.FeatureSetter = other.FeatureSetter
+	}
+
+	if other.Builder != "" {
+		n.Builder = other.Builder
+	}
+
+	if other.Constructor != "" {
+		n.Constructor = other.Constructor
+	}
+
+	if other.Build != "" {
+		n.Build = other.Build
+	}
+
+	if other.ToBuilder != "" {
+		n.ToBuilder = other.ToBuilder
+	}
+
+	if other.Option != "" {
+		n.Option = other.Option
+	}
+}
+
+// Override overrides the settings including the ones of every struct, e.g. by the command line flags.
+func (c *Config) Override(other *Config) {
+	c.Merge(other)
+
+	for name, st := range c.Structs {
+		if other.Features != nil {
+			st.Features = nil
+		}
+
+		if other.Mode != "" {
+			st.Mode = ""
+		}
+
+		if other.Validation != "" {
+			st.Validation = ""
+		}
+
+		c.Structs[name] = st
+	}
+}
+
+// Settings parses the config, the structs inherit the settings they don't override.
+func (c *Config) Settings() (*Settings, error) {
+	generator, err := parseSettings(c.Features, c.Mode, c.Validation, c.Naming)
+	if err != nil {
+		return nil, err
+	}
+
+	layout, err := labels.ParseLayout(c.Layout)
+	if err != nil {
+		return nil, err
+	}
+
+	outputDir, output := filepath.Split(c.Output)
+	if output == "" {
+		output = defaultOutput
+	}
+
+	if !strings.HasSuffix(output, ".go") {
+		return nil, fmt.Errorf("output='%s' must be a name of a Go file or a directory", c.Output)
+	}
+
+	if outputDir != "" {
+		if strings.Contains(outputDir, OutputNamePlaceholder) {
+			return nil, fmt.Errorf("output directory of the output='%s' can't contain %s", c.Output,
+				OutputNamePlaceholder)
+		}
+
+		outputDir = filepath.Clean(outputDir)
+	}
+
+	structs := make(map[string]service.Settings, len(c.Structs))
+
+	for name, st := range c.Structs {
+		features, mode, validation, naming := c.Features, c.Mode, c.Validation, c.Naming
+
+		if st.Features != nil {
+			features = st.Features
+		}
+
+		if st.Mode != "" {
+			mode = st.Mode
+		}
+
+		if st.Validation != "" {
+			validation = st.Validation
+		}
+
+		naming.Merge(st.Naming)
+
+		settings, err := parseSettings(features, mode, validation, naming)
+		if err != nil {
+			return nil, fmt.Errorf("parsing settings of the struct='%s': %w", name, err)
+		}
+
+		structs[name] = *settings
+	}
+
+	return &Settings{
+		Generator: *generator,
+		Structs:   structs,
+		Layout:    layout,
+		Output:    output,
+		OutputDir: outputDir,
+		Template:  c.Template,
+	}, nil
+}
+
+func parseSettings(features []string, mode, validation string, naming NamingConfig) (*service.Settings, error) {
+	parsedFeatures, err := labels.ParseFeatures(strings.Join(features, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	parsedMode, err := labels.ParseMode(mode)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedValidation, err := labels.ParseValidation(validation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.Settings{
+		Features:   parsedFeatures,
+		Mode:       parsedMode,
+		Validation: parsedValidation,
+		Naming: service.Naming{
+			Setter:        naming.Setter,
+			FeatureSetter: naming.FeatureSetter,
+			Builder:       naming.Builder,
+			Constructor:   naming.Constructor,
+			Build:         naming.Build,
+			ToBuilder:     naming.ToBuilder,
+			Option:        naming.Option,
+		},
+	}, nil
+}
+
+// GetOutputFileName returns the name of the output file for the source file or the package name.
+func (s *Settings) GetOutputFileName(name string) string {
+	return strings.ReplaceAll(s.Output, OutputNamePlaceholder, strings.TrimSuffix(name, ".go"))
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slavaavr/go-struct-builder/internal/labels"
+	"github.com/slavaavr/go-struct-builder/internal/service"
+)
+
+func TestLoad(t *testing.T) {
+	var (
+		root   = t.TempDir()
+		module = filepath.Join(root, "module")
+		pkg    = filepath.Join(module, "pkg")
+	)
+
+	require.NoError(t, os.MkdirAll(pkg, 0o700))
+
+	writeFile := func(dir, data string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, FileName), []byte(data), 0o600))
+	}
+
+	// the config files above the module root are ignored
+	writeFile(root, "mode: options")
+	writeFile(module, `
+features: [ptr, arr]
+validation: first
+layout: package
+template: templates/builder.tmpl
+naming:
+  setter: With{{.Field}}
+  build: Create
+structs:
+  A:
+    mode: step
+  B:
+    features: [opt]
+    validation: all`)
+	writeFile(pkg, `
+output: "{name}_gen.go"
+naming:
+  build: Make
+structs:
+  A:
+    features: []
+    naming:
+      builder: "{{.Struct}}Factory"`)
+
+	require.NoError(t, os.WriteFile(filepath.Join(module, "go.mod"), []byte("module test\n"), 0o600))
+
+	cfg, err := Load(pkg)
+	require.NoError(t, err)
+
+	expected := &Config{
+		Features:   []string{"ptr", "arr"},
+		Mode:       "",
+		Validation: "first",
+		Layout:     "package",
+		Output:     "{name}_gen.go",
+		Template:   filepath.Join(module, "templates", "builder.tmpl"),
+		Naming: NamingConfig{
+			Setter:        "With{{.Field}}",
+			FeatureSetter: "",
+			Builder:       "",
+			Constructor:   "",
+			Build:         "Make",
+			ToBuilder:     "",
+			Option:        "",
+		},
+		Structs: map[string]StructConfig{
+			"A": {
+				Features:   []string{},
+				Mode:       "step",
+				Validation: "",
+				Naming: NamingConfig{
+					Setter:        "",
+					FeatureSetter: "",
+					Builder:       "{{.Struct}}Factory",
+					Constructor:   "",
+					Build:         "",
+					ToBuilder:     "",
+					Option:        "",
+				},
+			},
+			"B": {Features: []string{"opt"}, Mode: "", Validation: "all", Naming: NamingConfig{}},
+		},
+	}
+	assert.Equal(t, expected, cfg, "values are not equal")
+
+	settings, err := cfg.Settings()
+	require.NoError(t, err)
+
+	naming := service.Naming{
+		Setter:        "With{{.Field}}",
+		FeatureSetter: "",
+		Builder:       "",
+		Constructor:   "",
+		Build:         "Make",
+		ToBuilder:     "",
+		Option:        "",
+	}
+	structNaming := naming
+	structNaming.Builder = "{{.Struct}}Factory"
+
+	expectedSettings := &Settings{
+		Generator: service.Settings{
+			Features:   []labels.Feature{labels.FeatureFlagPtr, labels.FeatureFlagArr},
+			Mode:       labels.ModeBuilder,
+			Validation: labels.ValidationFirst,
+			Naming:     naming,
+		},
+		Structs: map[string]service.Settings{
+			"A": {Features: nil, Mode: labels.ModeStep, Validation: labels.ValidationFirst, Naming: structNaming},
+			"B": {
+				Features:   []labels.Feature{labels.FeatureFlagOpt},
+				Mode:       labels.ModeBuilder,
+				Validation: labels.ValidationAll,
+				Naming:     naming,
+			},
+		},
+		Layout:    labels.LayoutPackage,
+		Output:    "{name}_gen.go",
+		OutputDir: "",
+		Template:  filepath.Join(module, "templates", "builder.tmpl"),
+	}
+	assert.Equal(t, expectedSettings, settings, "values are not equal")
+	assert.Equal(t, "input_gen.go", settings.GetOutputFileName("input.go"))
+
+	cfg.Override(&Config{
+		Features:   []string{"map"},
+		Mode:       "",
+		Validation: "all",
+		Layout:     "",
+		Output:     "",
+		Template:   "",
+		Naming:     NamingConfig{},
+		Structs:    nil,
+	})
+
+	settings, err = cfg.Settings()
+	require.NoError(t, err)
+
+	assert.Equal(t, []labels.Feature{labels.FeatureFlagMap}, settings.Structs["B"].Features)
+	assert.Equal(t, labels.ModeStep, settings.Structs["A"].Mode)
+	assert.Equal(t, labels.ValidationAll, settings.Structs["A"].Validation)
+}
+
+func TestLoad_OutputDir(t *testing.T) {
+	var (
+		module = t.TempDir()
+		pkg    = filepath.Join(module, "pkg")
+	)
+
+	require.NoError(t, os.MkdirAll(pkg, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(module, "go.mod"), []byte("module test\n"), 0o600))
+
+	cases := []struct {
+		name              string
+		output            string
+		expectedOutput    string
+		expectedOutputDir string
+	}{
+		{
+			name:              "file name",
+			output:            "{name}_gen.go",
+			expectedOutput:    "{name}_gen.go",
+			expectedOutputDir: "",
+		},
+		{
+			name:              "relative path",
+			output:            "../builders/{name}_gen.go",
+			expectedOutput:    "{name}_gen.go",
+			expectedOutputDir: filepath.Join(module, "builders"),
+		},
+		{
+			name:              "relative directory",
+			output:            "./builders/",
+			expectedOutput:    "{name}_builder.go",
+			expectedOutputDir: filepath.Join(pkg, "builders"),
+		},
+		{
+			name:              "absolute directory",
+			output:            "/builders/",
+			expectedOutput:    "{name}_builder.go",
+			expectedOutputDir: "/builders",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(filepath.Join(pkg, FileName), []byte("output: \""+c.output+"\""), 0o600))
+
+			cfg, err := Load(pkg)
+			require.NoError(t, err)
+
+			settings, err := cfg.Settings()
+			require.NoError(t, err)
+
+			assert.Equal(t, c.expectedOutput, settings.Output)
+			assert.Equal(t, c.expectedOutputDir, settings.OutputDir)
+		})
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	cases := []struct {
+		name        string
+		config      string
+		expectedErr error
+	}{
+		{
+			name:        "empty config",
+			config:      "",
+			expectedErr: nil,
+		},
+		{
+			name:        "invalid feature",
+			config:      "features: [ptr, some]",
+			expectedErr: fmt.Errorf("unable to parse feature='%s'", "some"),
+		},
+		{
+			name:   "invalid struct mode",
+			config: "structs: {A: {mode: fluent}}",
+			expectedErr: fmt.Errorf("parsing settings of the struct='%s': %w", "A",
+				fmt.Errorf("unable to parse mode='%s'", "fluent")),
+		},
+		{
+			name:   "invalid struct validation",
+			config: "structs: {A: {validation: last}}",
+			expectedErr: fmt.Errorf("parsing settings of the struct='%s': %w", "A",
+				fmt.Errorf("unable to parse validation='%s'", "last")),
+		},
+		{
+			name:        "output not a Go file",
+			config:      "output: '{name}.txt'",
+			expectedErr: errors.New("output='{name}.txt' must be a name of a Go file or a directory"),
+		},
+		{
+			name:        "output directory pattern",
+			config:      "output: /gen/{name}/builder.go",
+			expectedErr: errors.New("output directory of the output='/gen/{name}/builder.go' can't contain {name}"),
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module test\n"), 0o600))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, FileName), []byte(c.config), 0o600))
+
+			cfg, err := Load(dir)
+			require.NoError(t, err)
+
+			_, actualErr := cfg.Settings()
+			require.Equal(t, c.expectedErr, actualErr, "errors are not equal")
+		})
+	}
+}
+
+func TestLoad_UnknownField(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module test\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, FileName), []byte("featurez: [ptr]"), 0o600))
+
+	_, err := Load(dir)
+	require.ErrorContains(t, err, "field featurez not found")
+}
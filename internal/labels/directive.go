@@ -0,0 +1,176 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DirectiveBuilder is the comment marking a struct the builder is generated for,
+	// e.g. //gosb:builder mode=step features=ptr,arr
+	DirectiveBuilder = "//gosb:builder"
+	// DirectiveGenerate is the comment running the generator, which marks the struct it's attached to as well
+	DirectiveGenerate = "//" + GenerateCmd
+
+	DirectiveFeatures      = "features"
+	DirectiveMode          = "mode"
+	DirectiveValidation    = "validation"
+	DirectiveSetter        = "setter"
+	DirectiveFeatureSetter = "feature_setter"
+	DirectiveBuilderName   = "builder"
+	DirectiveConstructor   = "constructor"
+	DirectiveBuild         = "build"
+	DirectiveToBuilder     = "to_builder"
+	DirectiveOption        = "option"
+)
+
+// IsBuilderDirective reports whether the comment is a gosb:builder directive.
+func IsBuilderDirective(comment string) bool {
+	rest, ok := strings.CutPrefix(comment, DirectiveBuilder)
+
+	return ok && (rest == "" || unicode.IsSpace(rune(rest[0])))
+}
+
+// IsGenerateDirective reports whether the comment is a go:generate directive running gosb,
+// e.g. //go:generate gosb -source=input.go or //go:generate go run github.com/user/gosb/cmd/gosb@latest.
+func IsGenerateDirective(comment string) bool {
+	rest, ok := strings.CutPrefix(comment, DirectiveGenerate)
+	if !ok || rest == "" || !unicode.IsSpace(rune(rest[0])) {
+		return false
+	}
+
+	for _, word := range strings.Fields(rest) {
+		cmd, _, _ := strings.Cut(path.Base(word), "@")
+		if cmd == Gosb {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseDirective parses the space separated key=value options of the gosb:builder directive,
+// values containing spaces are double-quoted Go strings, e.g. setter="With{{ .Field }}".
+// The options are returned by their keys, the values of the features, mode and validation options are validated.
+func ParseDirective(comment string) (map[string]string, error) {
+	rest, ok := strings.CutPrefix(comment, DirectiveBuilder)
+	if !ok {
+		return nil, fmt.Errorf("comment='%s' is not a '%s' directive", comment, DirectiveBuilder)
+	}
+
+	opts, err := splitDirectiveOptions(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string, len(opts))
+
+	for _, opt := range opts {
+		key, value, hasValue := strings.Cut(opt, "=")
+		if !hasValue {
+			return nil, fmt.Errorf("directive option='%s' must have a value", key)
+		}
+
+		if _, ok = res[key]; ok {
+			return nil, fmt.Errorf("duplicated directive option='%s'", key)
+		}
+
+		if value, err = unquoteDirectiveValue(value); err != nil {
+			return nil, fmt.Errorf("parsing value of the directive option='%s': %w", key, err)
+		}
+
+		if err = checkDirectiveOption(key, value); err != nil {
+			return nil, err
+		}
+
+		res[key] = value
+	}
+
+	return res, nil
+}
+
+func checkDirectiveOption(key, value string) error {
+	switch key {
+	case DirectiveFeatures:
+		if _, err := ParseFeatures(value); err != nil {
+			return err
+		}
+
+	case DirectiveMode:
+		if _, err := ParseMode(value); err != nil {
+			return err
+		}
+
+	case DirectiveValidation:
+		if _, err := ParseValidation(value); err != nil {
+			return err
+		}
+
+	case DirectiveSetter, DirectiveFeatureSetter, DirectiveBuilderName, DirectiveConstructor, DirectiveBuild,
+		DirectiveToBuilder, DirectiveOption:
+		if value == "" {
+			return fmt.Errorf("empty value of the directive option='%s'", key)
+		}
+
+	default:
+		return fmt.Errorf("unknown directive option='%s'", key)
+	}
+
+	return nil
+}
+
+func unquoteDirectiveValue(value string) (string, error) {
+	if !strings.HasPrefix(value, `"`) {
+		return value, nil
+	}
+
+	return strconv.Unquote(value)
+}
+
+// splitDirectiveOptions splits space separated options, keeping spaces inside double quotes,
+// e.g. `mode=step setter="With{{ .Field }}"` results in [`mode=step`, `setter="With{{ .Field }}"`].
+func splitDirectiveOptions(s string) ([]string, error) {
+	var (
+		res     = make([]string, 0)
+		quoted  = false
+		escaped = false
+		opt     strings.Builder
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+
+		case quoted && r == '\\':
+			escaped = true
+
+		case r == '"':
+			quoted = !quoted
+
+		case !quoted && unicode.IsSpace(r):
+			if opt.Len() > 0 {
+				res = append(res, opt.String())
+				opt.Reset()
+			}
+
+			continue
+		}
+
+		opt.WriteRune(r)
+	}
+
+	if quoted {
+		return nil, errors.New("unterminated quoted value of the directive option")
+	}
+
+	if opt.Len() > 0 {
+		res = append(res, opt.String())
+	}
+
+	return res, nil
+}
@@ -0,0 +1,186 @@
+package labels
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDirective(t *testing.T) {
+	cases := []struct {
+		name        string
+		comment     string
+		expected    map[string]string
+		expectedErr error
+	}{
+		{
+			name:        "no options",
+			comment:     "//gosb:builder",
+			expected:    map[string]string{},
+			expectedErr: nil,
+		},
+		{
+			name:    "features and mode",
+			comment: "//gosb:builder  features=ptr,arr\tmode=step ",
+			expected: map[string]string{
+				"features": "ptr,arr",
+				"mode":     "step",
+			},
+			expectedErr: nil,
+		},
+		{
+			name:    "empty features",
+			comment: "//gosb:builder features=",
+			expected: map[string]string{
+				"features": "",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "naming",
+			comment: `//gosb:builder setter="With{{ .Field }}" feature_setter={{.Setter}}Value ` +
+				`builder={{.Struct}}Factory constructor=Make{{.Builder}} build=Create`,
+			expected: map[string]string{
+				"setter":         "With{{ .Field }}",
+				"feature_setter": "{{.Setter}}Value",
+				"builder":        "{{.Struct}}Factory",
+				"constructor":    "Make{{.Builder}}",
+				"build":          "Create",
+			},
+			expectedErr: nil,
+		},
+		{
+			name:    "escaped quote",
+			comment: `//gosb:builder setter="Set{{ \"x\" }}{{ .Field }}"`,
+			expected: map[string]string{
+				"setter": `Set{{ "x" }}{{ .Field }}`,
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "not a directive",
+			comment:     "//go:generate gosb",
+			expected:    nil,
+			expectedErr: errors.New("comment='//go:generate gosb' is not a '//gosb:builder' directive"),
+		},
+		{
+			name:        "unknown option",
+			comment:     "//gosb:builder kind=step",
+			expected:    nil,
+			expectedErr: errors.New("unknown directive option='kind'"),
+		},
+		{
+			name:        "option without value",
+			comment:     "//gosb:builder mode",
+			expected:    nil,
+			expectedErr: errors.New("directive option='mode' must have a value"),
+		},
+		{
+			name:        "duplicated option",
+			comment:     "//gosb:builder mode=step mode=options",
+			expected:    nil,
+			expectedErr: errors.New("duplicated directive option='mode'"),
+		},
+		{
+			name:        "invalid mode",
+			comment:     "//gosb:builder mode=steps",
+			expected:    nil,
+			expectedErr: errors.New("unable to parse mode='steps'"),
+		},
+		{
+			name:        "invalid validation",
+			comment:     "//gosb:builder validation=last",
+			expected:    nil,
+			expectedErr: errors.New("unable to parse validation='last'"),
+		},
+		{
+			name:        "invalid features",
+			comment:     "//gosb:builder features=ptr,array",
+			expected:    nil,
+			expectedErr: errors.New("unable to parse feature='array'"),
+		},
+		{
+			name:        "empty naming pattern",
+			comment:     "//gosb:builder builder=",
+			expected:    nil,
+			expectedErr: errors.New("empty value of the directive option='builder'"),
+		},
+		{
+			name:        "unterminated quote",
+			comment:     `//gosb:builder setter="With{{ .Field }}`,
+			expected:    nil,
+			expectedErr: errors.New("unterminated quoted value of the directive option"),
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			actual, actualErr := ParseDirective(c.comment)
+			require.Equal(t, c.expectedErr, actualErr, "errors are not equal")
+			assert.Equal(t, c.expected, actual, "values are not equal")
+		})
+	}
+}
+
+func TestIsDirective(t *testing.T) {
+	cases := []struct {
+		name             string
+		comment          string
+		expectedBuilder  bool
+		expectedGenerate bool
+	}{
+		{
+			name:             "builder directive",
+			comment:          "//gosb:builder",
+			expectedBuilder:  true,
+			expectedGenerate: false,
+		},
+		{
+			name:             "builder directive with options",
+			comment:          "//gosb:builder mode=step",
+			expectedBuilder:  true,
+			expectedGenerate: false,
+		},
+		{
+			name:             "other gosb directive",
+			comment:          "//gosb:builders",
+			expectedBuilder:  false,
+			expectedGenerate: false,
+		},
+		{
+			name:             "generate directive",
+			comment:          "//go:generate gosb -source=input.go",
+			expectedBuilder:  false,
+			expectedGenerate: true,
+		},
+		{
+			name:             "generate directive running the gosb package",
+			comment:          "//go:generate go run github.com/slavaavr/go-struct-builder/cmd/gosb@latest ./...",
+			expectedBuilder:  false,
+			expectedGenerate: true,
+		},
+		{
+			name:             "generate directive running another tool",
+			comment:          "//go:generate mockgen -source=gosb.go",
+			expectedBuilder:  false,
+			expectedGenerate: false,
+		},
+		{
+			name:             "comment mentioning gosb and go:generate",
+			comment:          "// the go:generate directive runs gosb",
+			expectedBuilder:  false,
+			expectedGenerate: false,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expectedBuilder, IsBuilderDirective(c.comment), "builder directives are not equal")
+			assert.Equal(t, c.expectedGenerate, IsGenerateDirective(c.comment), "generate directives are not equal")
+		})
+	}
+}
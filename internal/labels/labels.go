@@ -21,8 +21,82 @@ const (
 	FeatureFlagPtr Feature = "ptr"
 	FeatureFlagArr Feature = "arr"
 	FeatureFlagOpt Feature = "opt"
+	FeatureFlagMap Feature = "map"
+
+	LayoutFile    Layout = "file"
+	LayoutPackage Layout = "package"
+
+	ModeBuilder Mode = "builder"
+	ModeStep    Mode = "step"
+	ModeOptions Mode = "options"
+
+	ValidationAll   Validation = "all"
+	ValidationFirst Validation = "first"
 )
 
+// Mode defines the kind of code generated for a struct.
+type Mode string
+
+func (t Mode) String() string {
+	return string(t)
+}
+
+func ParseMode(s string) (Mode, error) {
+	switch strings.TrimSpace(s) {
+	case "", ModeBuilder.String():
+		return ModeBuilder, nil
+
+	case ModeStep.String():
+		return ModeStep, nil
+
+	case ModeOptions.String():
+		return ModeOptions, nil
+
+	default:
+		return "", fmt.Errorf("unable to parse mode='%s'", s)
+	}
+}
+
+// Validation defines whether all errors of building a struct are returned at once or only the first one.
+type Validation string
+
+func (t Validation) String() string {
+	return string(t)
+}
+
+func ParseValidation(s string) (Validation, error) {
+	switch strings.TrimSpace(s) {
+	case "", ValidationAll.String():
+		return ValidationAll, nil
+
+	case ValidationFirst.String():
+		return ValidationFirst, nil
+
+	default:
+		return "", fmt.Errorf("unable to parse validation='%s'", s)
+	}
+}
+
+// Layout defines how builders of package patterns are grouped into output files.
+type Layout string
+
+func (t Layout) String() string {
+	return string(t)
+}
+
+func ParseLayout(s string) (Layout, error) {
+	switch strings.TrimSpace(s) {
+	case "", LayoutFile.String():
+		return LayoutFile, nil
+
+	case LayoutPackage.String():
+		return LayoutPackage, nil
+
+	default:
+		return "", fmt.Errorf("unable to parse layout='%s'", s)
+	}
+}
+
 func ParseFeatures(s string) ([]Feature, error) {
 	if s == "" {
 		return nil, nil
@@ -44,6 +118,9 @@ func ParseFeatures(s string) ([]Feature, error) {
 		case FeatureFlagOpt.String():
 			res = append(res, FeatureFlagOpt)
 
+		case FeatureFlagMap.String():
+			res = append(res, FeatureFlagMap)
+
 		default:
 			return nil, fmt.Errorf("unable to parse feature='%s'", s)
 		}
@@ -39,6 +39,12 @@ func TestParseFeatures(t *testing.T) {
 			expected:    []Feature{FeatureFlagOpt},
 			expectedErr: nil,
 		},
+		{
+			name:        "map feature",
+			features:    "map",
+			expected:    []Feature{FeatureFlagMap},
+			expectedErr: nil,
+		},
 		{
 			name:        "multiple features",
 			features:    "opt,arr,ptr",
@@ -80,3 +86,138 @@ func TestParseFeatures(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLayout(t *testing.T) {
+	cases := []struct {
+		name        string
+		layout      string
+		expected    Layout
+		expectedErr error
+	}{
+		{
+			name:        "empty layout",
+			layout:      "",
+			expected:    LayoutFile,
+			expectedErr: nil,
+		},
+		{
+			name:        "file layout",
+			layout:      "file",
+			expected:    LayoutFile,
+			expectedErr: nil,
+		},
+		{
+			name:        "package layout",
+			layout:      " package",
+			expected:    LayoutPackage,
+			expectedErr: nil,
+		},
+		{
+			name:        "invalid layout",
+			layout:      "module",
+			expected:    "",
+			expectedErr: fmt.Errorf("unable to parse layout='%s'", "module"),
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			actual, actualErr := ParseLayout(c.layout)
+			require.Equal(t, c.expectedErr, actualErr, "errors are not equal")
+			assert.Equal(t, c.expected, actual, "values are not equal")
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	cases := []struct {
+		name        string
+		mode        string
+		expected    Mode
+		expectedErr error
+	}{
+		{
+			name:        "empty mode",
+			mode:        "",
+			expected:    ModeBuilder,
+			expectedErr: nil,
+		},
+		{
+			name:        "builder mode",
+			mode:        "builder",
+			expected:    ModeBuilder,
+			expectedErr: nil,
+		},
+		{
+			name:        "step mode",
+			mode:        "step ",
+			expected:    ModeStep,
+			expectedErr: nil,
+		},
+		{
+			name:        "options mode",
+			mode:        "options",
+			expected:    ModeOptions,
+			expectedErr: nil,
+		},
+		{
+			name:        "invalid mode",
+			mode:        "fluent",
+			expected:    "",
+			expectedErr: fmt.Errorf("unable to parse mode='%s'", "fluent"),
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			actual, actualErr := ParseMode(c.mode)
+			require.Equal(t, c.expectedErr, actualErr, "errors are not equal")
+			assert.Equal(t, c.expected, actual, "values are not equal")
+		})
+	}
+}
+
+func TestParseValidation(t *testing.T) {
+	cases := []struct {
+		name        string
+		validation  string
+		expected    Validation
+		expectedErr error
+	}{
+		{
+			name:        "empty validation",
+			validation:  "",
+			expected:    ValidationAll,
+			expectedErr: nil,
+		},
+		{
+			name:        "all validation",
+			validation:  "all",
+			expected:    ValidationAll,
+			expectedErr: nil,
+		},
+		{
+			name:        "first validation",
+			validation:  " first",
+			expected:    ValidationFirst,
+			expectedErr: nil,
+		},
+		{
+			name:        "invalid validation",
+			validation:  "last",
+			expected:    "",
+			expectedErr: fmt.Errorf("unable to parse validation='%s'", "last"),
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			actual, actualErr := ParseValidation(c.validation)
+			require.Equal(t, c.expectedErr, actualErr, "errors are not equal")
+			assert.Equal(t, c.expected, actual, "values are not equal")
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+const (
+	StructTagDefault  = "default"
+	StructTagSkip     = "-"
+	StructTagName     = "name"
+	StructTagReadonly = "readonly"
+
+	ValidationMin     = "min"
+	ValidationMax     = "max"
+	ValidationLen     = "len"
+	ValidationRegex   = "regex"
+	ValidationOneOf   = "oneof"
+	ValidationNonZero = "nonzero"
+)
+
+// FieldTag is a parsed value of the gosb struct tag, e.g. `gosb:"optional,default=10"`.
+type FieldTag struct {
+	Required bool
+	Optional bool
+	// Default is a Go expression of the default field value
+	Default     string
+	Validations []model.Validation
+	// Skip reports whether the field is left out of the generated code, e.g. `gosb:"-"`
+	Skip bool
+	// Name replaces the field name in the names of the methods setting the field
+	Name     string
+	Readonly bool
+}
+
+// ParseFieldTag parses the comma separated options of the gosb struct tag,
+// unknown, duplicated and conflicting options are reported as errors.
+func ParseFieldTag(s string) (FieldTag, error) {
+	var (
+		res  FieldTag
+		opts = splitTagOptions(s)
+		seen = make(map[string]bool, len(opts))
+	)
+
+	for _, opt := range opts {
+		key, value, hasValue := strings.Cut(opt, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if seen[key] && !isValidationRule(key) {
+			return FieldTag{}, fmt.Errorf("duplicated tag option='%s'", key)
+		}
+
+		seen[key] = true
+
+		if hasValue && isFlagOption(key) {
+			return FieldTag{}, fmt.Errorf("tag option='%s' doesn't accept a value", key)
+		}
+
+		switch key {
+		case StructTagSkip:
+			res.Skip = true
+
+		case StructTagRequired:
+			res.Required = true
+
+		case StructTagOptional:
+			res.Optional = true
+
+		case StructTagReadonly:
+			res.Readonly = true
+
+		case StructTagDefault:
+			if !hasValue || value == "" {
+				return FieldTag{}, fmt.Errorf("empty value of the tag option='%s'", key)
+			}
+
+			res.Default = value
+
+		case StructTagName:
+			if !hasValue || value == "" {
+				return FieldTag{}, fmt.Errorf("empty value of the tag option='%s'", key)
+			}
+
+			if !token.IsIdentifier(value) {
+				return FieldTag{}, fmt.Errorf("value='%s' of the tag option='%s' is not an identifier", value, key)
+			}
+
+			res.Name = value
+
+		case ValidationMin, ValidationMax, ValidationLen, ValidationRegex, ValidationOneOf, ValidationNonZero:
+			if err := checkValidationArg(key, value, hasValue); err != nil {
+				return FieldTag{}, err
+			}
+
+			res.Validations = append(res.Validations, model.Validation{
+				Rule: key,
+				Arg:  value,
+			})
+
+		default:
+			return FieldTag{}, fmt.Errorf("unknown tag option='%s'", key)
+		}
+	}
+
+	if err := checkFieldTag(res, len(opts)); err != nil {
+		return FieldTag{}, err
+	}
+
+	return res, nil
+}
+
+// checkFieldTag reports the options of the tag which can't be used together.
+func checkFieldTag(tag FieldTag, optsCount int) error {
+	switch {
+	case tag.Skip && optsCount > 1:
+		return fmt.Errorf("tag option='%s' can't be combined with other options", StructTagSkip)
+
+	case tag.Required && tag.Optional:
+		return fmt.Errorf("tag options='%s' and '%s' are conflicting", StructTagRequired, StructTagOptional)
+
+	case tag.Required && tag.Default != "":
+		return errors.New("required field can't have a default value")
+
+	case tag.Required && tag.Readonly:
+		return errors.New("required field can't be readonly")
+	}
+
+	return nil
+}
+
+func isFlagOption(key string) bool {
+	switch key {
+	case StructTagSkip, StructTagRequired, StructTagOptional, StructTagReadonly:
+		return true
+
+	default:
+		return false
+	}
+}
+
+func isValidationRule(key string) bool {
+	switch key {
+	case ValidationMin, ValidationMax, ValidationLen, ValidationRegex, ValidationOneOf, ValidationNonZero:
+		return true
+
+	default:
+		return false
+	}
+}
+
+func checkValidationArg(rule, arg string, hasArg bool) error {
+	if rule == ValidationNonZero {
+		if hasArg {
+			return fmt.Errorf("tag option='%s' doesn't accept a value", rule)
+		}
+
+		return nil
+	}
+
+	if arg == "" {
+		return fmt.Errorf("empty value of the tag option='%s'", rule)
+	}
+
+	switch rule {
+	case ValidationMin, ValidationMax:
+		if _, err := strconv.ParseFloat(arg, 64); err != nil {
+			return fmt.Errorf("value='%s' of the tag option='%s' is not a number", arg, rule)
+		}
+
+	case ValidationLen:
+		if _, err := strconv.ParseUint(arg, 10, 0); err != nil {
+			return fmt.Errorf("value='%s' of the tag option='%s' is not a length", arg, rule)
+		}
+
+	case ValidationRegex:
+		if _, err := regexp.Compile(arg); err != nil {
+			return fmt.Errorf("value='%s' of the tag option='%s' is not a regular expression: %w", arg, rule, err)
+		}
+	}
+
+	return nil
+}
+
+// splitTagOptions splits comma separated options, keeping commas inside quotes and parentheses,
+// e.g. `default="a,b",optional` results in [`default="a,b"`, `optional`].
+func splitTagOptions(s string) []string {
+	var (
+		res   = make([]string, 0)
+		depth = 0
+		quote rune
+		start = 0
+	)
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote && (i == 0 || s[i-1] != '\\') {
+				quote = 0
+			}
+
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+
+		case r == '(' || r == '[' || r == '{':
+			depth++
+
+		case r == ')' || r == ']' || r == '}':
+			depth--
+
+		case r == ',' && depth == 0:
+			res = appendTagOption(res, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return appendTagOption(res, s[start:])
+}
+
+func appendTagOption(opts []string, opt string) []string {
+	opt = strings.TrimSpace(opt)
+	if opt == "" {
+		return opts
+	}
+
+	return append(opts, opt)
+}
@@ -0,0 +1,360 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+func TestParseFieldTag(t *testing.T) {
+	cases := []struct {
+		name        string
+		tag         string
+		expected    FieldTag
+		expectedErr error
+	}{
+		{
+			name: "empty tag",
+			tag:  "",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "required",
+			tag:  "required",
+			expected: FieldTag{
+				Required:    true,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "optional",
+			tag:  "optional",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    true,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "default literal",
+			tag:  "default=10",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "10",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "default string with comma",
+			tag:  `optional, default="a,b"`,
+			expected: FieldTag{
+				Required:    false,
+				Optional:    true,
+				Default:     `"a,b"`,
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "default function call",
+			tag:  "default=time.Now(),optional",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    true,
+				Default:     "time.Now()",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "empty default",
+			tag:  "default=",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("empty value of the tag option='%s'", "default"),
+		},
+		{
+			name: "required field with default",
+			tag:  "required,default=1",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: errors.New("required field can't have a default value"),
+		},
+		{
+			name: "validations",
+			tag:  `min=1, max=10.5,len=3,regex=^[a-z]{1,3}$,oneof=a b c,nonzero`,
+			expected: FieldTag{
+				Required: false,
+				Optional: false,
+				Default:  "",
+				Validations: []model.Validation{
+					{Rule: "min", Arg: "1"},
+					{Rule: "max", Arg: "10.5"},
+					{Rule: "len", Arg: "3"},
+					{Rule: "regex", Arg: "^[a-z]{1,3}$"},
+					{Rule: "oneof", Arg: "a b c"},
+					{Rule: "nonzero", Arg: ""},
+				},
+				Skip:     false,
+				Name:     "",
+				Readonly: false,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "invalid min value",
+			tag:  "min=a",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("value='%s' of the tag option='%s' is not a number", "a", "min"),
+		},
+		{
+			name: "invalid len value",
+			tag:  "len=-1",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("value='%s' of the tag option='%s' is not a length", "-1", "len"),
+		},
+		{
+			name: "nonzero with value",
+			tag:  "nonzero=1",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("tag option='%s' doesn't accept a value", "nonzero"),
+		},
+		{
+			name: "empty oneof",
+			tag:  "oneof=",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("empty value of the tag option='%s'", "oneof"),
+		},
+		{
+			name: "skip",
+			tag:  " - ",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        true,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "name and readonly",
+			tag:  "name=Host,readonly",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "Host",
+				Readonly:    true,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "invalid name",
+			tag:  "name=a-b",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("value='%s' of the tag option='%s' is not an identifier", "a-b", "name"),
+		},
+		{
+			name: "required readonly field",
+			tag:  "readonly,required",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: errors.New("required field can't be readonly"),
+		},
+		{
+			name: "unknown option",
+			tag:  "requried",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("unknown tag option='%s'", "requried"),
+		},
+		{
+			name: "conflicting options",
+			tag:  "required,optional",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: errors.New("tag options='required' and 'optional' are conflicting"),
+		},
+		{
+			name: "duplicated option",
+			tag:  "optional,default=1,default=2",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("duplicated tag option='%s'", "default"),
+		},
+		{
+			name: "skip combined with other options",
+			tag:  "-,optional",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("tag option='%s' can't be combined with other options", "-"),
+		},
+		{
+			name: "option without value with value",
+			tag:  "required=true",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("tag option='%s' doesn't accept a value", "required"),
+		},
+		{
+			name: "empty name",
+			tag:  "name=",
+			expected: FieldTag{
+				Required:    false,
+				Optional:    false,
+				Default:     "",
+				Validations: nil,
+				Skip:        false,
+				Name:        "",
+				Readonly:    false,
+			},
+			expectedErr: fmt.Errorf("empty value of the tag option='%s'", "name"),
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			actual, actualErr := ParseFieldTag(c.tag)
+			require.Equal(t, c.expectedErr, actualErr, "errors are not equal")
+			assert.Equal(t, c.expected, actual, "values are not equal")
+		})
+	}
+}
@@ -6,6 +6,12 @@ type File struct {
 	Pkg     string
 	Imports []Import
 	Structs []Struct
+	// Others are the annotated structs of the other files of the package, the builders of which are generated
+	// into other output files, so the names generated for the Structs must not collide with theirs
+	Others []Struct
+	// Decls are the names of the package level declarations of the package the builders are generated into,
+	// except the ones of the generated files, e.g. the ones of gosb
+	Decls []string
 }
 
 type Import struct {
@@ -16,7 +22,22 @@ type Import struct {
 type Struct struct {
 	Name    string
 	Private bool
-	Fields  []Field
+	// Qualifier is the name the package of a struct declared outside the generated package is imported by, e.g. http
+	Qualifier  string
+	TypeParams []TypeParam
+	// Directive are the options of the gosb:builder comment of the struct, e.g. mode=step
+	Directive map[string]string
+	// Methods are the names of the methods declared for the struct, except the ones of the generated files
+	Methods []string
+	// NoCopy reports whether the struct contains a lock, e.g. a sync.Mutex field, so its values must not be copied
+	// and the builder isn't seeded from a struct value
+	NoCopy bool
+	Fields []Field
+}
+
+type TypeParam struct {
+	Name       string
+	Constraint string
 }
 
 type Field struct {
@@ -24,11 +45,48 @@ type Field struct {
 	Type     FieldType
 	Private  bool
 	Required bool
+	// Default is a Go expression of the value the field is initialized with
+	Default     string
+	Validations []Validation
+	// Nested is the builder of the gosb annotated struct the field type refers to
+	Nested *Nested
+	// Alias replaces the field name in the names of the methods setting the field, e.g. Host for SetHost
+	Alias string
+	// Readonly reports whether the field has no methods setting it
+	Readonly bool
 }
 
 type FieldType struct {
 	Name string
+	// Elem is the element type of pointer, array and option types, or the value type of map types
+	Elem string
+	// Key is the key type of map types
+	Key  string
 	Info TypeInfo
+	Kind TypeKind
+	// NoCopy reports whether the element of a pointer type contains a lock, e.g. *tls.Config,
+	// so the field is set only by a pointer
+	NoCopy bool
+}
+
+// Nested is a builder of a struct type of the same package.
+type Nested struct {
+	Struct  string
+	Private bool
+	// Required and Validated report whether the struct has required fields and fields with validation rules
+	Required  bool
+	Validated bool
+	// Nested are the builders of the struct fields, they're shared by the fields of the parsed structs
+	// and may refer back to the struct, so the graph of the builders can be cyclic
+	Nested []*Nested
+	// Directive are the options of the gosb:builder comment of the struct
+	Directive map[string]string
+}
+
+// Validation is a rule checked by the builder, e.g. min=1.
+type Validation struct {
+	Rule string
+	Arg  string
 }
 
 type TypeInfo int
@@ -38,4 +96,22 @@ const (
 	TypeInfoArray
 	TypeInfoPointer
 	TypeInfoOption
+	TypeInfoMap
+)
+
+// TypeKind is a kind of the underlying field type.
+type TypeKind int
+
+const (
+	TypeKindOther TypeKind = iota
+	TypeKindBool
+	TypeKindInt
+	TypeKindFloat
+	TypeKindString
+	TypeKindSlice
+	TypeKindMap
+	TypeKindArray
+	TypeKindStruct
+	// TypeKindNillable is a kind of pointers, channels, functions and interfaces
+	TypeKindNillable
 )
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	goparser "go/parser"
+	gotoken "go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const diffContextLines = 3
+
+// CheckOutput compares the generated code with the content of the output file
+// and returns a unified diff if the file is stale. A missing file is compared as an empty one.
+func CheckOutput(outputFile string, data []byte) (string, error) {
+	existing, err := os.ReadFile(outputFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("reading output file='%s': %w", outputFile, err)
+	}
+
+	if bytes.Equal(existing, data) {
+		return "", nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(existing),
+		FromFile: outputFile,
+		FromDate: "",
+		B:        splitLines(data),
+		ToFile:   outputFile + " (generated)",
+		ToDate:   "",
+		Eol:      "",
+		Context:  diffContextLines,
+	})
+	if err != nil {
+		return "", fmt.Errorf("making diff for output file='%s': %w", outputFile, err)
+	}
+
+	return diff, nil
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// GeneratedFile is the generated code along with the path of the output file it's written to.
+type GeneratedFile struct {
+	Path string
+	Data []byte
+}
+
+// CheckGeneratedDecls reports the package level declarations generated into the same package by different
+// output files, e.g. the builders of the structs of the same name declared by different packages,
+// which are written to a single output directory, as well as the ones declared by the other files
+// of the package, including the files generated by previous runs which aren't overwritten.
+func CheckGeneratedDecls(files []GeneratedFile) error {
+	type pkgKey struct {
+		dir  string
+		name string
+	}
+
+	var (
+		fset     = gotoken.NewFileSet()
+		outputs  = make(map[string]struct{}, len(files))
+		pkgKeys  = make([]pkgKey, 0)
+		pkgDecls = make(map[pkgKey]map[string]string)
+	)
+
+	for _, f := range files {
+		outputs[f.Path] = struct{}{}
+	}
+
+	for _, f := range files {
+		file, err := goparser.ParseFile(fset, f.Path, f.Data, goparser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("parsing generated code of the output file='%s': %w", f.Path, err)
+		}
+
+		key := pkgKey{
+			dir:  filepath.Dir(f.Path),
+			name: file.Name.Name,
+		}
+
+		decls, ok := pkgDecls[key]
+		if !ok {
+			decls = make(map[string]string)
+			pkgDecls[key] = decls
+			pkgKeys = append(pkgKeys, key)
+		}
+
+		for _, name := range getFileDecls(file) {
+			if other, ok := decls[name]; ok {
+				return fmt.Errorf("name='%s' of the output file='%s' is already generated into the output file='%s'",
+					name, f.Path, other)
+			}
+
+			decls[name] = f.Path
+		}
+	}
+
+	for _, key := range pkgKeys {
+		existing, err := parseDirFiles(key.dir, key.name)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range existing {
+			if _, ok := outputs[f.path]; ok {
+				continue
+			}
+
+			for _, name := range getFileDecls(f.file) {
+				if output, ok := pkgDecls[key][name]; ok {
+					return fmt.Errorf("name='%s' of the output file='%s' is already declared by the file='%s'",
+						name, output, f.path)
+				}
+			}
+		}
+	}
+
+	return nil
+}
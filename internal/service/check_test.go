@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOutput(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "input_builder.go")
+
+	cases := []struct {
+		name     string
+		existing *string
+		data     string
+		expected string
+	}{
+		{
+			name:     "up to date",
+			existing: ptr("package main\n\ntype A struct{}\n"),
+			data:     "package main\n\ntype A struct{}\n",
+			expected: "",
+		},
+		{
+			name:     "stale file",
+			existing: ptr("package main\n\ntype A struct{}\n"),
+			data:     "package main\n\ntype B struct{}\n",
+			expected: "--- " + outputFile + "\n" +
+				"+++ " + outputFile + " (generated)\n" +
+				"@@ -1,3 +1,3 @@\n" +
+				" package main\n" +
+				" \n" +
+				"-type A struct{}\n" +
+				"+type B struct{}\n",
+		},
+		{
+			name:     "missing file",
+			existing: nil,
+			data:     "package main\n",
+			expected: "--- " + outputFile + "\n" +
+				"+++ " + outputFile + " (generated)\n" +
+				"@@ -0,0 +1 @@\n" +
+				"+package main\n",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			_ = os.Remove(outputFile)
+
+			if c.existing != nil {
+				require.NoError(t, os.WriteFile(outputFile, []byte(*c.existing), 0o600))
+			}
+
+			actual, err := CheckOutput(outputFile, []byte(c.data))
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func TestCheckGeneratedDecls(t *testing.T) {
+	dir := t.TempDir()
+	generated := "// Code generated by go-struct-builder. DO NOT EDIT.\n\npackage out\n\n"
+
+	for name, data := range map[string]string{
+		"a_builder.go":   generated + "type UserBuilder struct{}\n",
+		"b_builder.go":   generated + "type AccountBuilder struct{}\n",
+		"out.go":         "package out\n\nfunc NewAccount() {}\n",
+		"out_test.go":    "package out_test\n\nfunc NewUser() {}\n",
+		"out_windows.go": "package out\n\nfunc NewUser() {}\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600))
+	}
+
+	cases := []struct {
+		name        string
+		files       []GeneratedFile
+		expectedErr error
+	}{
+		{
+			name: "builders of different packages",
+			files: []GeneratedFile{
+				{Path: "out/a_builder.go", Data: []byte("package out\n\ntype UserBuilder struct{}\n")},
+				{Path: "out/b_builder.go", Data: []byte("package out\n\ntype UserBuilder struct{}\n")},
+			},
+			expectedErr: errors.New("name='UserBuilder' of the output file='out/b_builder.go' " +
+				"is already generated into the output file='out/a_builder.go'"),
+		},
+		{
+			name: "functions of different packages",
+			files: []GeneratedFile{
+				{Path: "out/a_builder.go", Data: []byte("package out\n\nfunc NewUser() {}\n")},
+				{Path: "out/b_builder.go", Data: []byte("package out\n\nvar x, NewUser = 1, 2\n")},
+			},
+			expectedErr: errors.New("name='NewUser' of the output file='out/b_builder.go' " +
+				"is already generated into the output file='out/a_builder.go'"),
+		},
+		{
+			name: "different output directories",
+			files: []GeneratedFile{
+				{Path: "a/a_builder.go", Data: []byte("package a\n\ntype UserBuilder struct{}\n")},
+				{Path: "b/b_builder.go", Data: []byte("package b\n\ntype UserBuilder struct{}\n")},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "external test package",
+			files: []GeneratedFile{
+				{Path: "a/a_builder.go", Data: []byte("package a\n\ntype UserBuilder struct{}\n")},
+				{Path: "a/a_builder_test.go", Data: []byte("package a_test\n\ntype UserBuilder struct{}\n")},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "regenerated file of the output directory",
+			files: []GeneratedFile{
+				{Path: filepath.Join(dir, "a_builder.go"), Data: []byte("package out\n\nfunc NewUser() {}\n")},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "generated file of the output directory",
+			files: []GeneratedFile{
+				{Path: filepath.Join(dir, "c_builder.go"), Data: []byte("package out\n\ntype AccountBuilder struct{}\n")},
+			},
+			expectedErr: errors.New("name='AccountBuilder' of the output file='" + filepath.Join(dir, "c_builder.go") +
+				"' is already declared by the file='" + filepath.Join(dir, "b_builder.go") + "'"),
+		},
+		{
+			name: "declaration of the output directory",
+			files: []GeneratedFile{
+				{Path: filepath.Join(dir, "c_builder.go"), Data: []byte("package out\n\nfunc NewAccount() {}\n")},
+			},
+			expectedErr: errors.New("name='NewAccount' of the output file='" + filepath.Join(dir, "c_builder.go") +
+				"' is already declared by the file='" + filepath.Join(dir, "out.go") + "'"),
+		},
+		{
+			name: "methods",
+			files: []GeneratedFile{
+				{Path: "out/a_builder.go", Data: []byte("package out\n\nfunc (b *A) Build() {}\n")},
+				{Path: "out/b_builder.go", Data: []byte("package out\n\nfunc (b *B) Build() {}\n")},
+			},
+			expectedErr: nil,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			err := CheckGeneratedDecls(c.files)
+			require.Equal(t, c.expectedErr, err)
+		})
+	}
+}
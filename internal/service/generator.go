@@ -1,10 +1,11 @@
 package service
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"text/template"
 
 	toolsimports "golang.org/x/tools/imports"
 
@@ -12,24 +13,54 @@ import (
 	"github.com/slavaavr/go-struct-builder/internal/model"
 )
 
+// gosberrPkgPath is the import path of the package with errors returned by the generated builders.
+const gosberrPkgPath = "github.com/slavaavr/go-struct-builder/gosberr"
+
 type Generator interface {
 	Generate(f *model.File) ([]byte, error)
 }
 
+// Settings define the code generated for a struct.
+type Settings struct {
+	Features   []labels.Feature
+	Mode       labels.Mode
+	Validation labels.Validation
+	Naming     Naming
+}
+
 type generator struct {
-	buf    bytes.Buffer
-	indent string
+	settings       Settings
+	structSettings map[string]Settings
+	// template is the custom template redefining the templates of the DefaultTemplate
+	template string
 
+	// features, mode and naming are the settings of the struct being generated
 	features []labels.Feature
+	mode     labels.Mode
+	naming   Naming
+
+	// directives are the options of the gosb:builder directives of the structs and their nested builders
+	directives map[string]map[string]string
+
+	// namingTemplates are the parsed naming patterns, namingErr is the first error of generating a name
+	namingTemplates map[string]*template.Template
+	namingErr       error
 }
 
-func NewGenerator(
-	features []labels.Feature,
-) Generator {
+// NewGenerator creates a generator using the settings for every struct except the ones having
+// their own settings, which are looked up by the struct name. The templates defined by the custom
+// template text, if any, replace the ones of the DefaultTemplate.
+func NewGenerator(settings Settings, structSettings map[string]Settings, tmpl string) Generator {
 	return &generator{
-		buf:      bytes.Buffer{},
-		indent:   "",
-		features: features,
+		settings:        settings,
+		structSettings:  structSettings,
+		template:        tmpl,
+		features:        settings.Features,
+		mode:            settings.Mode,
+		naming:          settings.Naming,
+		directives:      make(map[string]map[string]string),
+		namingTemplates: make(map[string]*template.Template),
+		namingErr:       nil,
 	}
 }
 
@@ -38,54 +69,42 @@ func (g *generator) Generate(f *model.File) ([]byte, error) {
 		return nil, errors.New("no structs provided for generator")
 	}
 
-	defer func() {
-		g.buf.Reset()
+	// the imports are added to a copy of the file, which can be generated more than once, e.g. by gosb.Render
+	f = copyFile(f)
 
-		g.indent = ""
-	}()
+	g.setDirectives(f)
 
-	g.pf("// Code generated by go-struct-builder. DO NOT EDIT.")
-	g.pf("// Source: %v", f.Name)
-	g.pf("")
+	if err := g.checkOptionNames(f); err != nil {
+		return nil, err
+	}
 
-	g.pf("package %v", f.Pkg)
-	g.pf("")
+	if err := g.checkNames(f); err != nil {
+		return nil, err
+	}
 
-	if isFileHasStructWithRequiredField(f) {
+	if g.isFileHasFallibleStruct(f) {
 		f.Imports = append(f.Imports, model.Import{
 			Value: `"errors"`,
 			Alias: nil,
+		}, model.Import{
+			Value: strconv.Quote(gosberrPkgPath),
+			Alias: nil,
 		})
 	}
 
-	if len(f.Imports) > 0 {
-		g.pf("import (")
-		g.in()
-
-		for _, imp := range f.Imports {
-			if imp.Alias != nil {
-				g.pf("%s %s", *imp.Alias, imp.Value)
-			} else {
-				g.pf(imp.Value)
-			}
-		}
-
-		g.out()
-		g.pf(")")
-		g.pf("")
+	if isFileHasValidationRule(f, labels.ValidationRegex) {
+		f.Imports = append(f.Imports, model.Import{
+			Value: `"regexp"`,
+			Alias: nil,
+		})
 	}
 
-	for _, st := range f.Structs {
-		if !st.Private {
-			g.generateStructGetters(st)
-		}
-
-		g.generateBuilder(st)
-		g.pf("")
-		g.pf("")
+	data, err := g.executeTemplate(f)
+	if err != nil {
+		return nil, err
 	}
 
-	res, err := toolsimports.Process("", g.buf.Bytes(), nil)
+	res, err := toolsimports.Process("", data, nil)
 	if err != nil {
 		return nil, fmt.Errorf("formatting generated code: %w", err)
 	}
@@ -93,269 +112,245 @@ func (g *generator) Generate(f *model.File) ([]byte, error) {
 	return res, nil
 }
 
-func (g *generator) generateBuilder(st model.Struct) {
-	builderName := g.getBuilderName(st.Name)
-	requiredField2Index := g.getRequiredField2IndexMap(st)
+// copyFile returns a shallow copy of the file with its own imports.
+func copyFile(f *model.File) *model.File {
+	res := *f
+	res.Imports = append(make([]model.Import, 0, len(f.Imports)), f.Imports...)
 
-	g.generateBuilderStruct(builderName, st)
-	g.generateBuilderConstructor(builderName, requiredField2Index, st)
-	g.generateBuilderMethods(builderName, requiredField2Index, st)
-	g.generateBuildMethod(builderName, requiredField2Index, st)
+	return &res
 }
 
-func (g *generator) generateBuilderStruct(builderName string, st model.Struct) {
-	g.pf("type %s struct {", builderName)
-	g.in()
-	g.pf("x *%s", st.Name)
-	g.pf("mask []byte")
-	g.out()
-	g.pf("}")
-	g.pf("")
+// fieldSetter describes a method setting a field, along with the additional methods provided by features.
+type fieldSetter struct {
+	name string
+	// feature reports whether the method is provided by a feature
+	feature bool
+	param   string
+	// value is the assigned value, where the fieldPlaceholder stands for the field itself
+	value string
 }
 
-func (g *generator) generateBuilderConstructor(
-	builderName string,
-	requiredField2Index map[model.Field]int,
-	st model.Struct,
-) {
-	requiredFieldsMask := g.getRequiredFieldsMask(requiredField2Index, st)
+const fieldPlaceholder = "{field}"
 
-	if st.Private {
-		g.pf("func new%s() *%s {", makeStringCapital(builderName), builderName)
-	} else {
-		g.pf("func New%s() *%s {", builderName, builderName)
+// valueOf returns the value assigned to the target field.
+func (s fieldSetter) valueOf(target string) string {
+	return strings.ReplaceAll(s.value, fieldPlaceholder, target)
+}
+
+// getFieldSetters returns the methods setting the field, there are none for a readonly field.
+func (g *generator) getFieldSetters(fld model.Field) []fieldSetter {
+	if fld.Readonly {
+		return nil
 	}
 
-	g.in()
+	setterName, featureSetterName := g.getSetterName(fld)
+	res := []fieldSetter{
+		{
+			name:    setterName,
+			feature: false,
+			param:   "v " + fld.Type.Name,
+			value:   "v",
+		},
+	}
 
-	if isStructHasRequiredField(st) {
-		g.pf("/**")
-		g.in()
-		g.pf("Required fields:")
+	switch {
+	// a value containing a lock can't be passed to the setter
+	case fld.Type.Info == model.TypeInfoPointer && g.hasFeature(labels.FeatureFlagPtr) && !fld.Type.NoCopy:
+		res = append(res, fieldSetter{
+			name:    featureSetterName,
+			feature: true,
+			param:   "v " + fld.Type.Elem,
+			value:   "&v",
+		})
 
-		for _, fld := range st.Fields {
-			if fld.Required {
-				g.pf("%d) %s %s", requiredField2Index[fld], fld.Name, fld.Type.Name)
-			}
-		}
+	case fld.Type.Info == model.TypeInfoArray && g.hasFeature(labels.FeatureFlagArr):
+		res = append(res, fieldSetter{
+			name:    featureSetterName,
+			feature: true,
+			param:   "v ..." + fld.Type.Elem,
+			value:   "append(" + fieldPlaceholder + ", v...)",
+		})
 
-		g.out()
-		g.pf("*/")
-		g.pf("")
+	case fld.Type.Info == model.TypeInfoOption && g.hasFeature(labels.FeatureFlagOpt):
+		// the option type name is already qualified the way the source file imports the mo package
+		moPkgPrefix := strings.TrimSuffix(fld.Type.Name, fmt.Sprintf("%s[%s]", moOptionType, fld.Type.Elem))
+
+		res = append(res, fieldSetter{
+			name:    featureSetterName,
+			feature: true,
+			param:   "v " + fld.Type.Elem,
+			value:   moPkgPrefix + "Some(v)",
+		})
 	}
 
-	g.pf("return &%s{", builderName)
-	g.in()
-	g.pf("x: new(%s),", st.Name)
-	g.pf("mask: []byte{%s},", mapBytesToString(requiredFieldsMask))
-	g.out()
-	g.pf("}")
-	g.out()
-	g.pf("}")
-	g.pf("")
+	return res
 }
 
-func (g *generator) generateBuilderMethods(
-	builderName string,
-	requiredField2Index map[model.Field]int,
-	st model.Struct,
-) {
-	for _, fld := range st.Fields {
-		g.generateBuilderMethodByField(builderName, requiredField2Index, fld)
-
-		switch {
-		case fld.Type.Info == model.TypeInfoPointer && g.hasFeature(labels.FeatureFlagPtr):
-			g.generateBuilderMethodFeaturePtr(builderName, requiredField2Index, fld)
-
-		case fld.Type.Info == model.TypeInfoArray && g.hasFeature(labels.FeatureFlagArr):
-			g.generateBuilderMethodFeatureArr(builderName, requiredField2Index, fld)
+const bitsInByte = 8
 
-		case fld.Type.Info == model.TypeInfoOption && g.hasFeature(labels.FeatureFlagOpt):
-			g.generateBuilderMethodFeatureOpt(builderName, requiredField2Index, fld)
-		}
+// isFieldMasked reports whether the builder keeps track of providing the field by a mask bit, which is the case
+// for the required fields the builder may miss and the optional ones having validations, the rules of a field
+// are checked only once it's provided.
+func (g *generator) isFieldMasked(fld model.Field) bool {
+	if fld.Required {
+		return g.mode != labels.ModeStep
 	}
-}
 
-const (
-	setMaskBitPattern = "b.mask[%d/8] &= ^uint8(1 << %d %% 8)"
-)
+	return len(fld.Validations) > 0 && fld.Default == ""
+}
 
-func (g *generator) generateBuilderMethodByField(
-	builderName string,
-	requiredField2Index map[model.Field]int,
-	fld model.Field,
-) {
-	g.pf("func (b *%s) Set%s(v %s) *%s {", builderName, g.getMethodName(fld), fld.Type.Name, builderName)
-	g.in()
-	g.pf("b.x.%s = v", fld.Name)
+func (g *generator) getMaskedFieldsMask(
+	maskedField2Index map[string]int,
+	st model.Struct,
+) []byte {
+	res := make([]byte, len(maskedField2Index)/bitsInByte+1)
 
-	if fld.Required {
-		idx := requiredField2Index[fld]
-		g.pf(setMaskBitPattern, idx, idx)
+	for _, fld := range st.Fields {
+		if idx, ok := maskedField2Index[fld.Name]; ok {
+			res[idx/bitsInByte] |= 1 << (idx % bitsInByte)
+		}
 	}
 
-	g.pf("return b")
-	g.out()
-	g.pf("}")
-	g.pf("")
+	return res
 }
 
-func (g *generator) generateBuilderMethodFeaturePtr(
-	builderName string,
-	requiredField2Index map[model.Field]int,
-	fld model.Field,
-) {
-	fldType := strings.TrimPrefix(fld.Type.Name, "*")
+// getMaskedField2IndexMap returns the mask bit indexes of the fields, the required fields come first.
+func (g *generator) getMaskedField2IndexMap(st model.Struct) map[string]int {
+	i := 0
+	res := make(map[string]int)
 
-	g.pf("func (b *%s) Set%sV(v %s) *%s {", builderName, g.getMethodName(fld), fldType, builderName)
-	g.in()
-	g.pf("b.x.%s = &v", fld.Name)
+	for _, required := range []bool{true, false} {
+		for _, fld := range st.Fields {
+			if fld.Required == required && g.isFieldMasked(fld) {
+				i++
 
-	if fld.Required {
-		idx := requiredField2Index[fld]
-		g.pf(setMaskBitPattern, idx, idx)
+				res[fld.Name] = i
+			}
+		}
 	}
 
-	g.pf("return b")
-	g.out()
-	g.pf("}")
-	g.pf("")
+	return res
 }
 
-func (g *generator) generateBuilderMethodFeatureArr(
-	builderName string,
-	requiredField2Index map[model.Field]int,
-	fld model.Field,
-) {
-	fldType := strings.TrimPrefix(fld.Type.Name, "[]")
+// getMethodName returns the name of the field used in the names of the methods setting it.
+func (g *generator) getMethodName(fld model.Field) string {
+	if fld.Alias != "" {
+		return makeStringCapital(fld.Alias)
+	}
 
-	g.pf("func (b *%s) Set%sV(v ...%s) *%s {", builderName, g.getMethodName(fld), fldType, builderName)
-	g.in()
-	g.pf("b.x.%s = append(b.x.%s, v...)", fld.Name, fld.Name)
+	return makeStringCapital(fld.Name)
+}
 
-	if fld.Required {
-		idx := requiredField2Index[fld]
-		g.pf(setMaskBitPattern, idx, idx)
+// getGetterName returns the name of the getter method of a private field of a public struct,
+// empty for the other fields.
+func getGetterName(st model.Struct, fld model.Field) string {
+	if st.Private || !fld.Private {
+		return ""
 	}
 
-	g.pf("return b")
-	g.out()
-	g.pf("}")
-	g.pf("")
+	return makeStringCapital(fld.Name)
 }
 
-func (g *generator) generateBuilderMethodFeatureOpt(
-	builderName string,
-	requiredField2Index map[model.Field]int,
-	fld model.Field,
-) {
-	fldType := strings.TrimPrefix(fld.Type.Name, moOptionType+"[")
-	fldType = strings.TrimSuffix(fldType, "]")
+// useStructSettings switches the generator to the settings of the struct.
+func (g *generator) useStructSettings(structName string) {
+	settings := g.getStructSettings(structName)
 
-	g.pf("func (b *%s) Set%sV(v %s) *%s {", builderName, g.getMethodName(fld), fldType, builderName)
-	g.in()
-	g.pf("b.x.%s = mo.Some(v)", fld.Name)
+	g.features = settings.Features
+	g.mode = settings.Mode
+	g.naming = settings.Naming
+}
 
-	if fld.Required {
-		idx := requiredField2Index[fld]
-		g.pf(setMaskBitPattern, idx, idx)
+// getStructSettings returns the settings of the struct, the options of its gosb:builder directive
+// take precedence over the ones provided to the generator.
+func (g *generator) getStructSettings(structName string) Settings {
+	settings, ok := g.structSettings[structName]
+	if !ok {
+		settings = g.settings
 	}
 
-	g.pf("return b")
-	g.out()
-	g.pf("}")
-	g.pf("")
+	return applyDirective(settings, g.directives[structName])
 }
 
-func (g *generator) generateBuildMethod(
-	builderName string,
-	requiredField2Index map[model.Field]int,
-	st model.Struct,
-) {
-	if isStructHasRequiredField(st) {
-		g.pf("func (b *%s) Build() (*%s, error) {", builderName, st.Name)
-		g.in()
+// setDirectives collects the gosb:builder directive options of the structs of the file
+// along with the ones of the nested builders and the annotated structs of other files.
+func (g *generator) setDirectives(f *model.File) {
+	g.directives = make(map[string]map[string]string)
+
+	visited := make(map[string]struct{})
+
+	for _, st := range f.Structs {
+		if st.Directive != nil {
+			g.directives[st.Name] = st.Directive
+		}
 
 		for _, fld := range st.Fields {
-			if fld.Required {
-				idx := requiredField2Index[fld]
-				g.pf("if (b.mask[%d/8] & (1 << %d %% 8)) != 0 {", idx, idx)
-				g.in()
-				g.pf(`return nil, errors.New("%s.%s field is not provided")`, st.Name, fld.Name)
-				g.out()
-				g.pf("}")
-				g.pf("")
+			if fld.Nested != nil {
+				g.setNestedDirectives(fld.Nested, visited)
 			}
 		}
-
-		g.pf("return b.x, nil")
-		g.out()
-	} else {
-		g.pf("func (b *%s) Build() *%s {", builderName, st.Name)
-		g.in()
-		g.pf("return b.x")
-		g.out()
 	}
 
-	g.pf("}")
+	for _, st := range f.Others {
+		if st.Directive != nil {
+			g.directives[st.Name] = st.Directive
+		}
+	}
 }
 
-const bitsInByte = 8
-
-func (g *generator) getRequiredFieldsMask(
-	requiredField2Index map[model.Field]int,
-	st model.Struct,
-) []byte {
-	requiredFieldsCount := 0
-
-	for _, fld := range st.Fields {
-		if fld.Required {
-			requiredFieldsCount++
-		}
+// setNestedDirectives collects the directive options of the nested builder and the ones it uses in turn.
+func (g *generator) setNestedDirectives(nested *model.Nested, visited map[string]struct{}) {
+	if _, ok := visited[nested.Struct]; ok {
+		return
 	}
 
-	res := make([]byte, requiredFieldsCount/bitsInByte+1)
+	visited[nested.Struct] = struct{}{}
 
-	for _, fld := range st.Fields {
-		if fld.Required {
-			idx := requiredField2Index[fld]
-			res[idx/bitsInByte] |= 1 << idx % bitsInByte
-		}
+	if nested.Directive != nil {
+		g.directives[nested.Struct] = nested.Directive
 	}
 
-	return res
+	for _, tmp := range nested.Nested {
+		g.setNestedDirectives(tmp, visited)
+	}
 }
 
-func (g *generator) getRequiredField2IndexMap(st model.Struct) map[model.Field]int {
-	i := 0
-	res := make(map[model.Field]int)
+// applyDirective returns the settings overridden by the directive options, which are validated by the parser.
+func applyDirective(settings Settings, directive map[string]string) Settings {
+	for key, value := range directive {
+		switch key {
+		case labels.DirectiveFeatures:
+			settings.Features, _ = labels.ParseFeatures(value)
 
-	for _, fld := range st.Fields {
-		if fld.Required {
-			i++
+		case labels.DirectiveMode:
+			settings.Mode, _ = labels.ParseMode(value)
 
-			res[fld] = i
-		}
-	}
+		case labels.DirectiveValidation:
+			settings.Validation, _ = labels.ParseValidation(value)
 
-	return res
-}
+		case labels.DirectiveSetter:
+			settings.Naming.Setter = value
 
-func (g *generator) getMethodName(fld model.Field) string {
-	return makeStringCapital(fld.Name)
-}
+		case labels.DirectiveFeatureSetter:
+			settings.Naming.FeatureSetter = value
 
-func (g *generator) generateStructGetters(st model.Struct) {
-	for _, fld := range st.Fields {
-		if fld.Private {
-			g.pf("func (t *%s) %s() %s {", st.Name, makeStringCapital(fld.Name), fld.Type.Name)
-			g.in()
-			g.pf("return t.%s", fld.Name)
-			g.out()
-			g.pf("}")
-			g.pf("")
+		case labels.DirectiveBuilderName:
+			settings.Naming.Builder = value
+
+		case labels.DirectiveConstructor:
+			settings.Naming.Constructor = value
+
+		case labels.DirectiveBuild:
+			settings.Naming.Build = value
+
+		case labels.DirectiveToBuilder:
+			settings.Naming.ToBuilder = value
+
+		case labels.DirectiveOption:
+			settings.Naming.Option = value
 		}
 	}
+
+	return settings
 }
 
 func (g *generator) hasFeature(f labels.Feature) bool {
@@ -368,25 +363,51 @@ func (g *generator) hasFeature(f labels.Feature) bool {
 	return false
 }
 
-func (g *generator) getBuilderName(structName string) string {
-	return fmt.Sprintf("%sBuilder", structName)
+// getConstructorName returns the name of the function creating a value, which is private for a private struct.
+func (g *generator) getConstructorName(name string, private bool) string {
+	if private {
+		return "new" + makeStringCapital(name)
+	}
+
+	return "New" + name
 }
 
-func (g *generator) pf(format string, args ...interface{}) {
-	_, err := fmt.Fprintf(&g.buf, g.indent+format+"\n", args...)
-	if err != nil {
-		panic(fmt.Sprintf("error writing to the buf: %s", err))
+// getTypeParamsDecl returns the type parameter list of a generic struct, e.g. [K comparable, V any].
+func getTypeParamsDecl(st model.Struct) string {
+	if len(st.TypeParams) == 0 {
+		return ""
 	}
+
+	params := make([]string, 0, len(st.TypeParams))
+
+	for _, p := range st.TypeParams {
+		params = append(params, fmt.Sprintf("%s %s", p.Name, p.Constraint))
+	}
+
+	return "[" + strings.Join(params, ", ") + "]"
 }
 
-func (g *generator) in() {
-	g.indent += "\t"
+// getTypeArgs returns the type arguments to instantiate a generic struct with its own parameters, e.g. [K, V].
+func getTypeArgs(st model.Struct) string {
+	if len(st.TypeParams) == 0 {
+		return ""
+	}
+
+	args := make([]string, 0, len(st.TypeParams))
+
+	for _, p := range st.TypeParams {
+		args = append(args, p.Name)
+	}
+
+	return "[" + strings.Join(args, ", ") + "]"
 }
 
-func (g *generator) out() {
-	if len(g.indent) > 0 {
-		g.indent = g.indent[0 : len(g.indent)-1]
+func getStructType(st model.Struct) string {
+	if st.Qualifier != "" {
+		return st.Qualifier + "." + st.Name + getTypeArgs(st)
 	}
+
+	return st.Name + getTypeArgs(st)
 }
 
 func mapBytesToString(bs []byte) string {
@@ -399,9 +420,12 @@ func mapBytesToString(bs []byte) string {
 	return res.String()[1:]
 }
 
-func isFileHasStructWithRequiredField(f *model.File) bool {
-	for _, s := range f.Structs {
-		if isStructHasRequiredField(s) {
+// isFileHasFallibleStruct reports whether the generated code of any struct of the file returns errors.
+func (g *generator) isFileHasFallibleStruct(f *model.File) bool {
+	for _, st := range f.Structs {
+		g.useStructSettings(st.Name)
+
+		if g.isStructFallible(st) {
 			return true
 		}
 	}
@@ -419,6 +443,20 @@ func isStructHasRequiredField(st model.Struct) bool {
 	return false
 }
 
+func isStructHasDefaultValue(st model.Struct) bool {
+	for _, fld := range st.Fields {
+		if fld.Default != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func makeStringCapital(s string) string {
 	return strings.ToUpper(s[:1]) + s[1:]
 }
+
+func makeStringLower(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"github.com/slavaavr/go-struct-builder/internal/labels"
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+const (
+	// copySlice and copyMap are the ways the constructor of a builder seeded from a copy of a struct value
+	// detaches the fields which the builder methods modify in place, so that the builder never changes
+	// the original struct value. Appending to a full slice always allocates a new array.
+	copySlice = "slice"
+	copyMap   = "map"
+)
+
+// getCopy returns the way the field of the copied struct is detached, empty if it's not.
+func (g *generator) getCopy(fld model.Field) string {
+	switch {
+	case fld.Type.Info == model.TypeInfoArray && g.hasFeature(labels.FeatureFlagArr):
+		return copySlice
+
+	case fld.Type.Info == model.TypeInfoMap && g.hasFeature(labels.FeatureFlagMap):
+		return copyMap
+
+	default:
+		return ""
+	}
+}
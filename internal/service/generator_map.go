@@ -0,0 +1,26 @@
+package service
+
+import (
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+// getMapData returns the methods putting and deleting entries of a map field,
+// the map is allocated by the first call of any of them.
+func (g *generator) getMapData(fld model.Field) *MapData {
+	names := g.getMapMethodNames(fld)
+
+	return &MapData{
+		Put:    names[0],
+		PutAll: names[1],
+		Delete: names[2],
+		Key:    fld.Type.Key,
+		Elem:   fld.Type.Elem,
+	}
+}
+
+// getMapMethodNames returns the names of the methods putting, putting all and deleting entries of a map field.
+func (g *generator) getMapMethodNames(fld model.Field) []string {
+	name := g.getMethodName(fld)
+
+	return []string{"Put" + name, "PutAll" + name, "Delete" + name}
+}
@@ -0,0 +1,400 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"text/template"
+
+	"github.com/slavaavr/go-struct-builder/internal/labels"
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+// Naming define the names of the builder and step builder code by text/template patterns executed
+// with the NamingData, empty patterns stand for the default ones.
+type Naming struct {
+	// Setter is the name of the method setting a field, e.g. Set{{.Field}}
+	Setter string
+	// FeatureSetter is the name of the additional method provided by a feature, e.g. {{.Setter}}V
+	FeatureSetter string
+	// Builder is the name of the builder type, e.g. {{.Struct}}Builder
+	Builder string
+	// Constructor is the name of the function creating a builder, e.g. New{{.Builder}}
+	Constructor string
+	// Build is the name of the method building the struct, e.g. Build
+	Build string
+	// ToBuilder is the name of the struct method creating a builder seeded from the struct, e.g. ToBuilder,
+	// the NamingDisabled pattern turns the method off
+	ToBuilder string
+	// Option is the name of the functional option setting a field, e.g. With{{.Field}},
+	// the option provided by a feature is suffixed by V
+	Option string
+}
+
+// NamingData is the data the naming patterns are executed with. The names are capitalized,
+// so the first letter of the type and function names generated for a private struct is lowered afterwards.
+type NamingData struct {
+	// Struct is the name of the struct, it's set for the Builder, Constructor, Build, ToBuilder and Option patterns
+	Struct string
+	// Builder is the name of the builder, it's set for the Constructor and ToBuilder patterns
+	Builder string
+	// Field is the name of the field, it's set for the Setter, FeatureSetter and Option patterns
+	Field string
+	// Setter is the name of the setter, it's set for the FeatureSetter pattern
+	Setter string
+}
+
+const (
+	defaultSetterNaming        = "Set{{.Field}}"
+	defaultFeatureSetterNaming = "{{.Setter}}V"
+	defaultBuilderNaming       = "{{.Struct}}Builder"
+	defaultConstructorNaming   = "New{{.Builder}}"
+	defaultBuildNaming         = "Build"
+	defaultToBuilderNaming     = "ToBuilder"
+	defaultOptionNaming        = "With{{.Field}}"
+)
+
+// NamingDisabled is the pattern turning off the generated code it names, which is supported by the ToBuilder pattern.
+const NamingDisabled = "-"
+
+// withDefaults returns the naming with the default patterns instead of the empty ones.
+func (n Naming) withDefaults() Naming {
+	if n.Setter == "" {
+		n.Setter = defaultSetterNaming
+	}
+
+	if n.FeatureSetter == "" {
+		n.FeatureSetter = defaultFeatureSetterNaming
+	}
+
+	if n.Builder == "" {
+		n.Builder = defaultBuilderNaming
+	}
+
+	if n.Constructor == "" {
+		n.Constructor = defaultConstructorNaming
+	}
+
+	if n.Build == "" {
+		n.Build = defaultBuildNaming
+	}
+
+	if n.ToBuilder == "" {
+		n.ToBuilder = defaultToBuilderNaming
+	}
+
+	if n.Option == "" {
+		n.Option = defaultOptionNaming
+	}
+
+	return n
+}
+
+func (g *generator) getBuilderName(structName string, private bool) string {
+	naming := g.getStructSettings(structName).Naming.withDefaults()
+
+	return g.executeNaming(naming.Builder, NamingData{
+		Struct:  makeStringCapital(structName),
+		Builder: "",
+		Field:   "",
+		Setter:  "",
+	}, private)
+}
+
+func (g *generator) getBuilderConstructorName(structName string, private bool) string {
+	naming := g.getStructSettings(structName).Naming.withDefaults()
+
+	return g.executeNaming(naming.Constructor, NamingData{
+		Struct:  makeStringCapital(structName),
+		Builder: makeStringCapital(g.getBuilderName(structName, private)),
+		Field:   "",
+		Setter:  "",
+	}, private)
+}
+
+func (g *generator) getBuildName(structName string) string {
+	naming := g.getStructSettings(structName).Naming.withDefaults()
+
+	return g.executeNaming(naming.Build, NamingData{
+		Struct:  makeStringCapital(structName),
+		Builder: "",
+		Field:   "",
+		Setter:  "",
+	}, false)
+}
+
+// getToBuilderName returns the name of the struct method creating a builder seeded from the struct,
+// empty if the method is turned off, the struct is declared by another package or can't be copied.
+func (g *generator) getToBuilderName(st model.Struct) string {
+	naming := g.getStructSettings(st.Name).Naming.withDefaults()
+	if naming.ToBuilder == NamingDisabled || st.Qualifier != "" || st.NoCopy {
+		return ""
+	}
+
+	return g.executeNaming(naming.ToBuilder, NamingData{
+		Struct:  makeStringCapital(st.Name),
+		Builder: makeStringCapital(g.getBuilderName(st.Name, st.Private)),
+		Field:   "",
+		Setter:  "",
+	}, st.Private)
+}
+
+// getSetterName returns the name of the method setting the field of the struct being generated,
+// along with the name of the additional method provided by a feature.
+func (g *generator) getSetterName(fld model.Field) (string, string) {
+	naming := g.naming.withDefaults()
+	data := NamingData{
+		Struct:  "",
+		Builder: "",
+		Field:   g.getMethodName(fld),
+		Setter:  "",
+	}
+
+	data.Setter = g.executeNaming(naming.Setter, data, false)
+
+	return data.Setter, g.executeNaming(naming.FeatureSetter, data, false)
+}
+
+// executeNaming returns the name generated by the pattern, the first error is kept by the generator
+// to be reported by checkNames.
+func (g *generator) executeNaming(pattern string, data NamingData, private bool) string {
+	tmpl, ok := g.namingTemplates[pattern]
+	if !ok {
+		var err error
+
+		tmpl, err = template.New("naming").Option("missingkey=error").Parse(pattern)
+		if err != nil {
+			g.setNamingErr(fmt.Errorf("parsing naming pattern='%s': %w", pattern, err))
+
+			return ""
+		}
+
+		g.namingTemplates[pattern] = tmpl
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, data); err != nil {
+		g.setNamingErr(fmt.Errorf("executing naming pattern='%s': %w", pattern, err))
+
+		return ""
+	}
+
+	res := buf.String()
+	if private {
+		res = makeStringLower(res)
+	}
+
+	if !token.IsIdentifier(res) {
+		g.setNamingErr(fmt.Errorf("name='%s' generated by the naming pattern='%s' is not an identifier", res, pattern))
+	}
+
+	return res
+}
+
+func (g *generator) setNamingErr(err error) {
+	if g.namingErr == nil {
+		g.namingErr = err
+	}
+}
+
+// checkNames reports invalid naming patterns along with the generated declarations and builder methods
+// having the same name, the declarations are checked against the ones generated for other files of the package
+// and the ones declared by the package.
+func (g *generator) checkNames(f *model.File) error {
+	name2Struct := make(map[string]string, len(f.Structs)+len(f.Others))
+
+	for _, st := range f.Structs {
+		name2Struct[st.Name] = st.Name
+	}
+
+	for _, st := range f.Others {
+		name2Struct[st.Name] = st.Name
+	}
+
+	// the names generated for other files are reported by their own generation
+	for _, st := range f.Others {
+		g.useStructSettings(st.Name)
+
+		for _, name := range g.getDeclNames(st) {
+			if _, ok := name2Struct[name]; !ok {
+				name2Struct[name] = st.Name
+			}
+		}
+	}
+
+	decls := make(map[string]struct{}, len(f.Decls))
+
+	for _, name := range f.Decls {
+		decls[name] = struct{}{}
+	}
+
+	g.namingErr = nil
+
+	for _, st := range f.Structs {
+		g.useStructSettings(st.Name)
+
+		for _, name := range g.getDeclNames(st) {
+			if other, ok := name2Struct[name]; ok && g.namingErr == nil {
+				return fmt.Errorf("name='%s' of the struct='%s' is already used by the struct='%s'",
+					name, st.Name, other)
+			}
+
+			if _, ok := decls[name]; ok && g.namingErr == nil {
+				return fmt.Errorf("name='%s' of the struct='%s' is already declared by the package", name, st.Name)
+			}
+
+			name2Struct[name] = st.Name
+		}
+
+		methods := make(map[string]struct{})
+
+		for _, name := range g.getMethodNames(st) {
+			if _, ok := methods[name]; ok && g.namingErr == nil {
+				return fmt.Errorf("method='%s' of the struct='%s' builder is generated more than once", name, st.Name)
+			}
+
+			methods[name] = struct{}{}
+		}
+
+		if err := g.checkStructMethods(st); err != nil && g.namingErr == nil {
+			return err
+		}
+
+		if g.namingErr != nil {
+			return fmt.Errorf("naming the struct='%s': %w", st.Name, g.namingErr)
+		}
+	}
+
+	return nil
+}
+
+// checkStructMethods reports the methods generated for the struct having the same name
+// or colliding with the fields and the methods declared for the struct.
+func (g *generator) checkStructMethods(st model.Struct) error {
+	declared := make(map[string]struct{}, len(st.Fields)+len(st.Methods))
+
+	for _, fld := range st.Fields {
+		declared[fld.Name] = struct{}{}
+	}
+
+	for _, name := range st.Methods {
+		declared[name] = struct{}{}
+	}
+
+	generated := make(map[string]struct{})
+
+	for _, name := range g.getStructMethodNames(st) {
+		if _, ok := declared[name]; ok {
+			return fmt.Errorf("method='%s' generated for the struct='%s' is already declared by it", name, st.Name)
+		}
+
+		if _, ok := generated[name]; ok {
+			return fmt.Errorf("method='%s' of the struct='%s' is generated more than once", name, st.Name)
+		}
+
+		generated[name] = struct{}{}
+	}
+
+	return nil
+}
+
+// getStructMethodNames returns the names of the methods generated for the struct, the getters and ToBuilder().
+func (g *generator) getStructMethodNames(st model.Struct) []string {
+	res := make([]string, 0)
+
+	for _, fld := range st.Fields {
+		if name := getGetterName(st, fld); name != "" {
+			res = append(res, name)
+		}
+	}
+
+	if g.mode == labels.ModeBuilder {
+		if name := g.getToBuilderName(st); name != "" {
+			res = append(res, name)
+		}
+	}
+
+	return res
+}
+
+// getDeclNames returns the names of the package level declarations generated for the struct.
+func (g *generator) getDeclNames(st model.Struct) []string {
+	res := g.getBuilderDeclNames(st)
+
+	for _, re := range getRegexps(st) {
+		res = append(res, re.Name)
+	}
+
+	return res
+}
+
+// getBuilderDeclNames returns the names of the types and functions of the builder, the step builder
+// or the functional options of the struct.
+func (g *generator) getBuilderDeclNames(st model.Struct) []string {
+	switch g.mode {
+	case labels.ModeOptions:
+		res := []string{g.getOptionName(st), getOptionsHolderName(st), g.getConstructorName(st.Name, st.Private)}
+
+		for _, fld := range st.Fields {
+			for _, setter := range g.getOptionSetters(st, fld) {
+				res = append(res, setter.name)
+			}
+		}
+
+		return res
+
+	case labels.ModeStep:
+		builderName := g.getBuilderName(st.Name, st.Private)
+		res := []string{
+			getBuildStepName(builderName),
+			getStepImplName(builderName),
+			g.getBuilderConstructorName(st.Name, st.Private),
+		}
+
+		for _, fld := range getRequiredFields(st) {
+			res = append(res, g.getStepName(builderName, fld))
+		}
+
+		return res
+
+	default:
+		constructorName := g.getBuilderConstructorName(st.Name, st.Private)
+		res := []string{g.getBuilderName(st.Name, st.Private), constructorName}
+
+		if !st.NoCopy {
+			res = append(res, constructorName+"From")
+		}
+
+		return res
+	}
+}
+
+// getMethodNames returns the names of the builder methods.
+func (g *generator) getMethodNames(st model.Struct) []string {
+	if g.mode == labels.ModeOptions {
+		return nil
+	}
+
+	res := []string{g.getBuildName(st.Name)}
+
+	for _, fld := range st.Fields {
+		for _, setter := range g.getFieldSetters(fld) {
+			res = append(res, setter.name)
+		}
+
+		if g.mode != labels.ModeBuilder || fld.Readonly {
+			continue
+		}
+
+		if fld.Type.Info == model.TypeInfoMap && g.hasFeature(labels.FeatureFlagMap) {
+			res = append(res, g.getMapMethodNames(fld)...)
+		}
+
+		if g.isNested(fld) {
+			res = append(res, g.getNestedMethodName(fld))
+		}
+	}
+
+	return res
+}
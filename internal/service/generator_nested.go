@@ -0,0 +1,77 @@
+package service
+
+import (
+	"github.com/slavaavr/go-struct-builder/internal/labels"
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+// getNestedData returns the method setting the field to the struct built by its own builder,
+// which is configured by the passed function.
+func (g *generator) getNestedData(fld model.Field) *NestedData {
+	value := "*v"
+	if fld.Type.Info == model.TypeInfoPointer {
+		value = "v"
+	}
+
+	return &NestedData{
+		Method:      g.getNestedMethodName(fld),
+		Builder:     g.getBuilderName(fld.Nested.Struct, fld.Nested.Private),
+		Constructor: g.getBuilderConstructorName(fld.Nested.Struct, fld.Nested.Private),
+		Build:       g.getBuildName(fld.Nested.Struct),
+		Fallible:    g.isNestedFallible(fld),
+		Value:       value,
+	}
+}
+
+func (g *generator) getNestedMethodName(fld model.Field) string {
+	return "With" + g.getMethodName(fld)
+}
+
+// getNestedErrName returns the name of the builder field keeping the error of the nested builder.
+func getNestedErrName(fld model.Field) string {
+	return "err" + fld.Name
+}
+
+// isNested reports whether the field is set by the nested builder, which is the case
+// when the nested struct is generated in the builder mode.
+func (g *generator) isNested(fld model.Field) bool {
+	return fld.Nested != nil && g.getStructSettings(fld.Nested.Struct).Mode == labels.ModeBuilder
+}
+
+// isNestedFallible reports whether the Build() method of the nested builder returns an error.
+func (g *generator) isNestedFallible(fld model.Field) bool {
+	return g.isNested(fld) && g.isBuilderFallible(fld.Nested, make(map[string]struct{}))
+}
+
+// isBuilderFallible reports whether the Build() method of the builder returns an error, which is the case
+// for structs having required fields, validations or fields set by such builders, the same way isStructFallible
+// tells it for the builder mode. The visited structs break the cycles of the nested builders.
+func (g *generator) isBuilderFallible(nested *model.Nested, visited map[string]struct{}) bool {
+	if nested.Required || nested.Validated {
+		return true
+	}
+
+	visited[nested.Struct] = struct{}{}
+
+	for _, tmp := range nested.Nested {
+		if _, ok := visited[tmp.Struct]; ok || g.getStructSettings(tmp.Struct).Mode != labels.ModeBuilder {
+			continue
+		}
+
+		if g.isBuilderFallible(tmp, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *generator) isStructHasFallibleNested(st model.Struct) bool {
+	for _, fld := range st.Fields {
+		if g.isNestedFallible(fld) {
+			return true
+		}
+	}
+
+	return false
+}
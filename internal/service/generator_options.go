@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/slavaavr/go-struct-builder/internal/labels"
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+// getOptionSetters returns the functions creating options of the field, which are named by the option pattern,
+// the setter and feature setter patterns of the builders don't affect them.
+func (g *generator) getOptionSetters(st model.Struct, fld model.Field) []fieldSetter {
+	naming := g.getStructSettings(st.Name).Naming.withDefaults()
+	name := g.executeNaming(naming.Option, NamingData{
+		Struct:  makeStringCapital(st.Name),
+		Builder: "",
+		Field:   g.getMethodName(fld),
+		Setter:  "",
+	}, st.Private)
+
+	setters := g.getFieldSetters(fld)
+
+	for i := range setters {
+		setters[i].name = name
+
+		if setters[i].feature {
+			setters[i].name += "V"
+		}
+	}
+
+	return setters
+}
+
+func (g *generator) getOptionName(st model.Struct) string {
+	return st.Name + "Option"
+}
+
+// getOptionsHolderName returns the name of the type the functional options of the struct are applied to,
+// the options keep track of the provided required fields the same way the builder does.
+func getOptionsHolderName(st model.Struct) string {
+	return makeStringLower(st.Name) + "Options"
+}
+
+// checkOptionNames reports option functions of different structs of the package having the same name.
+func (g *generator) checkOptionNames(f *model.File) error {
+	name2Struct := make(map[string]string)
+
+	for _, st := range f.Others {
+		g.useStructSettings(st.Name)
+
+		if g.mode != labels.ModeOptions {
+			continue
+		}
+
+		for _, fld := range st.Fields {
+			for _, setter := range g.getOptionSetters(st, fld) {
+				if _, ok := name2Struct[setter.name]; !ok {
+					name2Struct[setter.name] = st.Name
+				}
+			}
+		}
+	}
+
+	for _, st := range f.Structs {
+		g.useStructSettings(st.Name)
+
+		if g.mode != labels.ModeOptions {
+			continue
+		}
+
+		for _, fld := range st.Fields {
+			for _, setter := range g.getOptionSetters(st, fld) {
+				if other, ok := name2Struct[setter.name]; ok {
+					return fmt.Errorf("option function='%s' of the struct='%s' is already generated for the struct='%s'",
+						setter.name, st.Name, other)
+				}
+
+				name2Struct[setter.name] = st.Name
+			}
+		}
+	}
+
+	return nil
+}
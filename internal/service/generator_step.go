@@ -0,0 +1,86 @@
+package service
+
+import (
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+// setSteps sets the step interfaces of a builder asking for the required fields one by one,
+// so a missing required field is a compile error rather than a Build() error.
+func (g *generator) setSteps(st *StructData) {
+	var (
+		builderName = g.getBuilderName(st.Struct.Name, st.Struct.Private)
+		buildStep   = getBuildStepName(builderName)
+		buildType   = buildStep + st.TypeArgs
+		step        = 0
+	)
+
+	st.Steps = make([]StepData, 0, len(st.Required))
+	st.FirstStep = buildType
+
+	for i, fld := range st.Required {
+		name := g.getStepName(builderName, fld.Field)
+
+		if i == 0 {
+			st.FirstStep = name + st.TypeArgs
+		} else {
+			st.Steps[i-1].Next = name + st.TypeArgs
+		}
+
+		st.Steps = append(st.Steps, StepData{
+			Name:   name,
+			Type:   name + st.TypeArgs,
+			Next:   buildType,
+			Fields: []FieldData{fld},
+		})
+	}
+
+	st.BuildStep = StepData{
+		Name:   buildStep,
+		Type:   buildType,
+		Next:   buildType,
+		Fields: make([]FieldData, 0),
+	}
+
+	for i, fld := range st.Fields {
+		st.Fields[i].Next = buildType
+
+		if !fld.Field.Required {
+			st.BuildStep.Fields = append(st.BuildStep.Fields, fld)
+
+			continue
+		}
+
+		step++
+
+		if step < len(st.Steps) {
+			st.Fields[i].Next = st.Steps[step].Type
+		}
+	}
+}
+
+// getStepName returns the name of the step interface asking for the required field.
+func (g *generator) getStepName(builderName string, fld model.Field) string {
+	return builderName + g.getMethodName(fld) + "Step"
+}
+
+// getBuildStepName returns the name of the last step interface setting the optional fields and building the struct.
+func getBuildStepName(builderName string) string {
+	return builderName + "BuildStep"
+}
+
+// getStepImplName returns the name of the type implementing the step interfaces.
+func getStepImplName(builderName string) string {
+	return makeStringLower(builderName) + "Impl"
+}
+
+func getRequiredFields(st model.Struct) []model.Field {
+	res := make([]model.Field, 0, len(st.Fields))
+
+	for _, fld := range st.Fields {
+		if fld.Required {
+			res = append(res, fld)
+		}
+	}
+
+	return res
+}
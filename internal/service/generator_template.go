@@ -0,0 +1,397 @@
+package service
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"text/template"
+
+	"github.com/slavaavr/go-struct-builder/internal/labels"
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+// DefaultTemplate is the template the output is generated from by default.
+// It defines the following templates, any of them can be redefined by a custom template:
+//   - file: the whole output, executed with the TemplateData
+//   - header: the leading comment along with the package clause, executed with the TemplateData
+//   - imports: the import declaration, executed with the TemplateData
+//   - struct: the code generated for a struct, executed with the model.Struct
+//
+// The code of a struct is rendered by the templates executed with the StructData, e.g. builder, constructor,
+// setters and build, the ones rendering a field are executed with the FieldTemplateData or SetterTemplateData.
+//
+//go:embed templates/default.tmpl
+var DefaultTemplate string
+
+// rootTemplateName is the name of the template executed to generate the output.
+const rootTemplateName = "file"
+
+// TemplateData is the data the file template is executed with.
+type TemplateData struct {
+	// File is the source file, its imports include the ones the generated code requires
+	File *model.File
+}
+
+// StructData is the data the templates of the struct code are executed with, the names are the ones
+// of the mode the struct is generated by.
+type StructData struct {
+	Struct model.Struct
+	Mode   labels.Mode
+	// Validation is the validation style, which returns either all errors of building the struct or the first one
+	Validation labels.Validation
+	// Type is the struct type, e.g. model.A[T]
+	Type string
+	// TypeParams is the type parameter list of a generic struct, e.g. [T any], TypeArgs are its arguments, e.g. [T]
+	TypeParams string
+	TypeArgs   string
+	// Builder is the name of the builder type, which is the step builder implementation for the step mode
+	// and the type holding the struct value for the options mode, BuilderType is instantiated by the TypeArgs
+	Builder     string
+	BuilderType string
+	// Constructor is the name of the function creating the builder, or the struct for the options mode
+	Constructor string
+	// From is the name of the constructor seeding the builder from a struct value, ToBuilder is the name
+	// of the struct method calling it, empty if the method isn't generated
+	From      string
+	ToBuilder string
+	Build     string
+	// Fallible reports whether the struct is built along with an error
+	Fallible bool
+	// Mask is the initial mask of the fields which are not provided yet, e.g. 0x6, EmptyMask has all of them provided
+	Mask      string
+	EmptyMask string
+	// Option is the name of the functional option type, OptionType is instantiated by the TypeArgs
+	Option     string
+	OptionType string
+	// Steps are the step interfaces asking for the required fields, the BuildStep one sets the optional fields,
+	// FirstStep is the type returned by the constructor of the step builder
+	Steps     []StepData
+	BuildStep StepData
+	FirstStep string
+	Fields    []FieldData
+	// Masked are the fields tracked by the mask bits, Required and Defaults are the fields which are required
+	// and the ones having default values
+	Masked   []FieldData
+	Required []FieldData
+	Defaults []FieldData
+	Regexps  []RegexpData
+}
+
+// FieldData is the data of a field of the StructData.
+type FieldData struct {
+	Field model.Field
+	Name  string
+	Type  string
+	// Index is the index of the mask bit of a field tracked by the mask, zero for the other fields
+	Index   int
+	Setters []SetterData
+	// Getter is the name of the getter method of a private field of a public struct, empty for the other fields
+	Getter string
+	// Copy is the way the From constructor detaches the field of the copied struct, slice or map, empty if it's not
+	Copy string
+	// Err is the name of the builder field keeping the error of the fallible nested builder
+	Err         string
+	Map         *MapData
+	Nested      *NestedData
+	Validations []ValidationData
+	// Next is the step type returned by the setters of the step builder implementation
+	Next string
+}
+
+// SetterData describes a builder method, functional option or step method setting a field.
+type SetterData struct {
+	Name string
+	// Feature reports whether the setter is provided by a feature
+	Feature bool
+	Param   string
+	// Value is the value assigned to the field, e.g. append(b.x.F1, v...)
+	Value string
+}
+
+// MapData are the names of the methods putting and deleting entries of a map field.
+type MapData struct {
+	Put    string
+	PutAll string
+	Delete string
+	Key    string
+	Elem   string
+}
+
+// NestedData describes the method setting a field to the struct built by the nested builder.
+type NestedData struct {
+	Method      string
+	Builder     string
+	Constructor string
+	Build       string
+	Fallible    bool
+	// Value is the value assigned to the field from the built struct pointer v, e.g. *v
+	Value string
+}
+
+// ValidationData is a validation rule of a field along with the condition failing it.
+type ValidationData struct {
+	Rule string
+	Cond string
+	Msg  string
+}
+
+// RegexpData is the package level variable of a compiled regex validation rule.
+type RegexpData struct {
+	Name string
+	// Pattern is the quoted regular expression
+	Pattern string
+}
+
+// StepData is a step interface of the step builder, which setters of the fields return the Next step type.
+type StepData struct {
+	Name   string
+	Type   string
+	Next   string
+	Fields []FieldData
+}
+
+// FieldTemplateData is the data the templates of a field are executed with.
+type FieldTemplateData struct {
+	Struct StructData
+	Field  FieldData
+}
+
+// SetterTemplateData is the data the setter template is executed with.
+type SetterTemplateData struct {
+	Struct StructData
+	Field  FieldData
+	Setter SetterData
+}
+
+// StepTemplateData is the data the step template is executed with.
+type StepTemplateData struct {
+	Struct StructData
+	Step   StepData
+}
+
+// ErrTemplateData is the data the err template is executed with, Err is the expression of the error.
+type ErrTemplateData struct {
+	Struct StructData
+	Err    string
+}
+
+// parseTemplate parses the default template along with the custom one redefining its templates.
+func (g *generator) parseTemplate() (*template.Template, error) {
+	res, err := template.New("default").Funcs(g.templateFuncs()).Parse(DefaultTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing default template: %w", err)
+	}
+
+	if g.template == "" {
+		return res, nil
+	}
+
+	if res, err = res.New("custom").Parse(g.template); err != nil {
+		return nil, fmt.Errorf("parsing custom template: %w", err)
+	}
+
+	return res, nil
+}
+
+// executeTemplate generates the output of the file by the template.
+func (g *generator) executeTemplate(f *model.File) ([]byte, error) {
+	tmpl, err := g.parseTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	if err = tmpl.ExecuteTemplate(&buf, rootTemplateName, TemplateData{File: f}); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// templateFuncs returns the functions available in the templates, the ones taking a struct
+// use the settings of the struct.
+func (g *generator) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"structData": g.getStructData,
+		"withField": func(st StructData, fld FieldData) FieldTemplateData {
+			return FieldTemplateData{Struct: st, Field: fld}
+		},
+		"withSetter": func(st StructData, fld FieldData, setter SetterData) SetterTemplateData {
+			return SetterTemplateData{Struct: st, Field: fld, Setter: setter}
+		},
+		"withStep": func(st StructData, step StepData) StepTemplateData {
+			return StepTemplateData{Struct: st, Step: step}
+		},
+		"withErr": func(st StructData, err string) ErrTemplateData {
+			return ErrTemplateData{Struct: st, Err: err}
+		},
+		"mode": func(st model.Struct) labels.Mode {
+			return g.getStructSettings(st.Name).Mode
+		},
+		"hasFeature": func(st model.Struct, f labels.Feature) bool {
+			g.useStructSettings(st.Name)
+
+			return g.hasFeature(f)
+		},
+		"builderName": func(st model.Struct) string {
+			return g.getBuilderName(st.Name, st.Private)
+		},
+		"constructorName": func(st model.Struct) string {
+			return g.getBuilderConstructorName(st.Name, st.Private)
+		},
+		"structType":     getStructType,
+		"typeParamsDecl": getTypeParamsDecl,
+		"typeArgs":       getTypeArgs,
+		"capital":        makeStringCapital,
+		"lower":          makeStringLower,
+	}
+}
+
+// getStructData returns the data the templates of the struct code are executed with.
+func (g *generator) getStructData(st model.Struct) StructData {
+	g.useStructSettings(st.Name)
+
+	var (
+		maskedField2Index = g.getMaskedField2IndexMap(st)
+		mask              = g.getMaskedFieldsMask(maskedField2Index, st)
+		res               = StructData{
+			Struct:      st,
+			Mode:        g.mode,
+			Validation:  g.getStructSettings(st.Name).Validation,
+			Type:        getStructType(st),
+			TypeParams:  getTypeParamsDecl(st),
+			TypeArgs:    getTypeArgs(st),
+			Builder:     g.getBuilderName(st.Name, st.Private),
+			BuilderType: "",
+			Constructor: g.getBuilderConstructorName(st.Name, st.Private),
+			From:        "",
+			ToBuilder:   "",
+			Build:       g.getBuildName(st.Name),
+			Fallible:    g.isStructFallible(st),
+			Mask:        mapBytesToString(mask),
+			EmptyMask:   mapBytesToString(make([]byte, len(mask))),
+			Option:      "",
+			OptionType:  "",
+			Steps:       nil,
+			BuildStep:   StepData{Name: "", Type: "", Next: "", Fields: nil},
+			FirstStep:   "",
+			Fields:      make([]FieldData, 0, len(st.Fields)),
+			Required:    make([]FieldData, 0),
+			Defaults:    make([]FieldData, 0),
+			Regexps:     getRegexps(st),
+		}
+	)
+
+	for _, fld := range st.Fields {
+		data := g.getFieldData(st, fld, maskedField2Index[fld.Name])
+
+		res.Fields = append(res.Fields, data)
+
+		if data.Index != 0 {
+			res.Masked = append(res.Masked, data)
+		}
+
+		if fld.Required {
+			res.Required = append(res.Required, data)
+		}
+
+		if fld.Default != "" {
+			res.Defaults = append(res.Defaults, data)
+		}
+	}
+
+	switch g.mode {
+	case labels.ModeOptions:
+		res.Builder = getOptionsHolderName(st)
+		res.Constructor = g.getConstructorName(st.Name, st.Private)
+		res.Option = g.getOptionName(st)
+		res.OptionType = res.Option + res.TypeArgs
+
+	case labels.ModeStep:
+		res.Builder = getStepImplName(res.Builder)
+		g.setSteps(&res)
+
+	default:
+		// a struct containing a lock can't be copied by value, so the builder isn't seeded from it
+		if !st.NoCopy {
+			res.From = res.Constructor + "From"
+			res.ToBuilder = g.getToBuilderName(st)
+		}
+	}
+
+	res.BuilderType = res.Builder + res.TypeArgs
+
+	return res
+}
+
+// getFieldData returns the data of the field of the struct being generated, the index is the one
+// of the mask bit of a field tracked by the mask.
+func (g *generator) getFieldData(st model.Struct, fld model.Field, index int) FieldData {
+	res := FieldData{
+		Field:       fld,
+		Name:        fld.Name,
+		Type:        fld.Type.Name,
+		Index:       index,
+		Setters:     nil,
+		Getter:      "",
+		Copy:        g.getCopy(fld),
+		Err:         "",
+		Map:         nil,
+		Nested:      nil,
+		Validations: getValidations(st, fld),
+		Next:        "",
+	}
+
+	res.Getter = getGetterName(st, fld)
+
+	setters := g.getFieldSetters(fld)
+	if g.mode == labels.ModeOptions {
+		setters = g.getOptionSetters(st, fld)
+	}
+
+	for _, setter := range setters {
+		res.Setters = append(res.Setters, SetterData{
+			Name:    setter.name,
+			Feature: setter.feature,
+			Param:   setter.param,
+			Value:   setter.valueOf("b.x." + fld.Name),
+		})
+	}
+
+	if g.mode != labels.ModeBuilder {
+		return res
+	}
+
+	if g.isNestedFallible(fld) {
+		res.Err = getNestedErrName(fld)
+	}
+
+	if fld.Readonly {
+		return res
+	}
+
+	if fld.Type.Info == model.TypeInfoMap && g.hasFeature(labels.FeatureFlagMap) {
+		res.Map = g.getMapData(fld)
+	}
+
+	if g.isNested(fld) {
+		res.Nested = g.getNestedData(fld)
+	}
+
+	return res
+}
+
+// isStructFallible reports whether the struct is built along with an error by the mode it's generated by.
+func (g *generator) isStructFallible(st model.Struct) bool {
+	switch g.mode {
+	case labels.ModeStep:
+		return isStructHasValidation(st)
+
+	case labels.ModeOptions:
+		return isStructHasRequiredField(st) || isStructHasValidation(st)
+
+	default:
+		return isStructHasRequiredField(st) || isStructHasValidation(st) || g.isStructHasFallibleNested(st)
+	}
+}
@@ -1,9 +1,10 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,16 +20,20 @@ func TestGenerator_Generate(t *testing.T) {
 	}
 
 	cases := []struct {
-		name        string
-		source      string
-		features    []labels.Feature
-		expectedErr error
+		name           string
+		source         string
+		features       []labels.Feature
+		mode           labels.Mode
+		structSettings map[string]Settings
+		expectedErr    error
 	}{
 		{
-			name:        "empty file",
-			source:      `package main`,
-			features:    nil,
-			expectedErr: errors.New("no structs provided for generator"),
+			name:           "empty file",
+			source:         `package main`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    errors.New("no structs provided for generator"),
 		},
 		{
 			name: "no go:generate comment",
@@ -38,8 +43,10 @@ func TestGenerator_Generate(t *testing.T) {
 			type A struct {
 				F1 int
 			}`,
-			features:    nil,
-			expectedErr: errors.New("no structs provided for generator"),
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    errors.New("no structs provided for generator"),
 		},
 		{
 			name: "basic example",
@@ -51,8 +58,10 @@ func TestGenerator_Generate(t *testing.T) {
 				F1 int
 				F2 string
 			}`,
-			features:    nil,
-			expectedErr: nil,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
 		},
 		{
 			name: "private fields",
@@ -64,8 +73,10 @@ func TestGenerator_Generate(t *testing.T) {
 				f1 int
 				f2 string
 			}`,
-			features:    nil,
-			expectedErr: nil,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
 		},
 		{
 			name: "field tags",
@@ -79,8 +90,10 @@ func TestGenerator_Generate(t *testing.T) {
 				F3 int` + "`gosb:\"optional\"`" + `
 				F4 *int` + "`gosb:\"required\"`" + `
 			}`,
-			features:    nil,
-			expectedErr: nil,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
 		},
 		{
 			name: "two builders",
@@ -100,8 +113,10 @@ func TestGenerator_Generate(t *testing.T) {
 			type C struct {
 				F3 int
 			}`,
-			features:    nil,
-			expectedErr: nil,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
 		},
 		{
 			name: "optional fields",
@@ -114,8 +129,10 @@ func TestGenerator_Generate(t *testing.T) {
 				F2 *string
 				F3 *float64
 			}`,
-			features:    nil,
-			expectedErr: nil,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
 		},
 		{
 			name: "generics",
@@ -132,8 +149,10 @@ func TestGenerator_Generate(t *testing.T) {
 				F2 genericType[string]
 				F3 genericType[genericType[string]]
 			}`,
-			features:    nil,
-			expectedErr: nil,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
 		},
 		{
 			name: "features",
@@ -155,7 +174,107 @@ func TestGenerator_Generate(t *testing.T) {
 				labels.FeatureFlagArr,
 				labels.FeatureFlagOpt,
 			},
-			expectedErr: nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "pointer to a lock",
+			source: `
+			package main
+
+			import "sync"
+
+			//go:generate gosb -source=input.go -features=ptr
+			type A struct {
+				F1 *sync.Mutex
+				F2 *int
+			}`,
+			features: []labels.Feature{
+				labels.FeatureFlagPtr,
+			},
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "map feature",
+			source: `
+			package main
+
+			type Labels map[string]int
+
+			//go:generate gosb -source=input.go -features=map
+			type A struct {
+				F1 map[string][]int
+				F2 Labels ` + "`gosb:\"optional\"`" + `
+			}`,
+			features: []labels.Feature{
+				labels.FeatureFlagMap,
+			},
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "aliased option import",
+			source: `
+			package main
+
+			import m "github.com/samber/mo"
+
+			//go:generate gosb -source=input.go -features=arr,opt
+			type A struct {
+				F1 Ints
+				F2 m.Option[string]
+			}
+
+			type Ints []int`,
+			features: []labels.Feature{
+				labels.FeatureFlagArr,
+				labels.FeatureFlagOpt,
+			},
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "generic struct",
+			source: `
+			package main
+
+			import "github.com/samber/mo"
+
+			//go:generate gosb -source=input.go -features=ptr,arr,opt
+			type A[T any, N ~int | ~int64] struct {
+				F1 T
+				F2 *T
+				F3 []T
+				F4 mo.Option[N]
+				f5 N
+			}`,
+			features: []labels.Feature{
+				labels.FeatureFlagPtr,
+				labels.FeatureFlagArr,
+				labels.FeatureFlagOpt,
+			},
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "private generic struct",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type page[T any] struct {
+				Items []T
+			}`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
 		},
 		{
 			name: "private struct",
@@ -167,8 +286,10 @@ func TestGenerator_Generate(t *testing.T) {
 				f1 int
 				f2 string
 			}`,
-			features:    nil,
-			expectedErr: nil,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
 		},
 		{
 			name: "struct embedding",
@@ -195,8 +316,10 @@ func TestGenerator_Generate(t *testing.T) {
 			type C struct {
 				F1 int
 			}`,
-			features:    nil,
-			expectedErr: nil,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
 		},
 		{
 			name: "type alias",
@@ -208,8 +331,10 @@ func TestGenerator_Generate(t *testing.T) {
 			type A struct {
 				F1 t1.Time
 			}`,
-			features:    nil,
-			expectedErr: nil,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
 		},
 		{
 			name: "unused import",
@@ -227,32 +352,655 @@ func TestGenerator_Generate(t *testing.T) {
 			type B struct {
 				F2 t2.Time
 			}`,
-			features:    nil,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "default values",
+			source: `
+			package main
+
+			import "time"
+
+			const defaultName = "name"
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 int
+				F2 string ` + "`gosb:\"default=defaultName\"`" + `
+				F3 time.Duration ` + "`gosb:\"default=time.Second\"`" + `
+			}`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "step builder default values",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go -mode=step
+			type A struct {
+				F1 int
+				F2 string ` + "`gosb:\"default=\\\"name\\\"\"`" + `
+			}`,
+			features:       nil,
+			mode:           labels.ModeStep,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "validations",
+			source: `
+			package main
+
+			import "time"
+
+			type Name string
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 int ` + "`gosb:\"min=1,max=10\"`" + `
+				F2 []string ` + "`gosb:\"optional,min=1,len=2\"`" + `
+				F3 Name ` + "`gosb:\"regex=^[a-z]{1,3}$\"`" + `
+				F4 string ` + "`gosb:\"oneof=a b\"`" + `
+				F5 float64 ` + "`gosb:\"oneof=0.5 1\"`" + `
+				F6 time.Time ` + "`gosb:\"nonzero\"`" + `
+				F7 *int ` + "`gosb:\"nonzero\"`" + `
+			}`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "first validation",
+			source: `
+			package main
+
+			//gosb:builder validation=first
+			type A struct {
+				F1 int ` + "`gosb:\"min=1\"`" + `
+				F2 B
+				F3 *C
+			}
+
+			//gosb:builder validation=first
+			type B struct {
+				F1 []string ` + "`gosb:\"optional,len=2\"`" + `
+			}
+
+			//gosb:builder mode=step validation=first
+			type C struct {
+				F1 string ` + "`gosb:\"oneof=a b\"`" + `
+			}
+
+			//gosb:builder mode=options
+			type D struct {
+				F1 int ` + "`gosb:\"max=1\"`" + `
+			}`,
+			features: nil,
+			mode:     labels.ModeBuilder,
+			structSettings: map[string]Settings{
+				"D": {Features: nil, Mode: labels.ModeOptions, Validation: labels.ValidationFirst},
+			},
 			expectedErr: nil,
 		},
+		{
+			name: "step builder validations",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go -mode=step
+			type A struct {
+				F1 *int
+				F2 string ` + "`gosb:\"optional,nonzero\"`" + `
+			}`,
+			features:       nil,
+			mode:           labels.ModeStep,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "nested builders",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 Address
+				F2 *Address
+				F3 c
+			}
+
+			//go:generate gosb -source=input.go
+			type Address struct {
+				City string
+			}
+
+			//go:generate gosb -source=input.go
+			type c struct {
+				F4 *int
+			}`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "nested builders of nested structs generated by other modes",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 B
+			}
+
+			//go:generate gosb -source=input.go
+			type B struct {
+				F2 C ` + "`gosb:\"optional\"`" + `
+				F3 *D
+			}
+
+			//gosb:builder mode=step
+			type C struct {
+				F4 int
+			}
+
+			//go:generate gosb -source=input.go
+			type D struct {
+				F5 *B
+			}`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "options",
+			source: `
+			package main
+
+			import "github.com/samber/mo"
+
+			//go:generate gosb -source=input.go -features=ptr,arr,opt -mode=options
+			type A struct {
+				F1 int ` + "`gosb:\"min=1\"`" + `
+				F2 *string
+				F3 []int
+				F4 mo.Option[int]
+				f5 string ` + "`gosb:\"default=\\\"name\\\"\"`" + `
+			}
+
+			//go:generate gosb -source=input.go -features=ptr,arr,opt -mode=options
+			type c[T any] struct {
+				F6 *T
+			}`,
+			features: []labels.Feature{
+				labels.FeatureFlagPtr,
+				labels.FeatureFlagArr,
+				labels.FeatureFlagOpt,
+			},
+			mode:           labels.ModeOptions,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "options of different structs with the same field name",
+			source: `
+			package main
+
+			//gosb:builder option=With{{.Struct}}{{.Field}}
+			type A struct {
+				F1 int
+			}
+
+			//gosb:builder option=With{{.Struct}}{{.Field}}
+			type B struct {
+				F1 string
+			}`,
+			features:       nil,
+			mode:           labels.ModeOptions,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "regexp variables collision",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				BC string ` + "`gosb:\"regex=^a$\"`" + `
+			}
+
+			//go:generate gosb -source=input.go
+			type AB struct {
+				C string ` + "`gosb:\"regex=^b$\"`" + `
+			}`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    errors.New("name='aBCRegexp' of the struct='AB' is already used by the struct='A'"),
+		},
+		{
+			name: "regexp variable declared by the package",
+			source: `
+			package main
+
+			import "regexp"
+
+			var userEmailRegexp = regexp.MustCompile("@")
+
+			//go:generate gosb -source=input.go
+			type User struct {
+				Email string ` + "`gosb:\"regex=@\"`" + `
+			}`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    errors.New("name='userEmailRegexp' of the struct='User' is already declared by the package"),
+		},
+		{
+			name: "options naming collision",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go -mode=options
+			type A struct {
+				F1 int
+			}
+
+			//go:generate gosb -source=input.go -mode=options
+			type B struct {
+				F1 string
+			}`,
+			features:       nil,
+			mode:           labels.ModeOptions,
+			structSettings: nil,
+			expectedErr: errors.New(
+				"option function='WithF1' of the struct='B' is already generated for the struct='A'"),
+		},
+		{
+			name: "struct settings",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 []int
+				F2 B
+			}
+
+			//go:generate gosb -source=input.go
+			type B struct {
+				F3 *int
+			}
+
+			//go:generate gosb -source=input.go
+			type C struct {
+				F4 int
+			}`,
+			features: []labels.Feature{
+				labels.FeatureFlagArr,
+			},
+			mode: labels.ModeBuilder,
+			structSettings: map[string]Settings{
+				"B": {Features: []labels.Feature{labels.FeatureFlagPtr}, Mode: labels.ModeOptions},
+				"C": {Features: nil, Mode: labels.ModeStep},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "multi-name fields",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1, F2 int
+				f3, F4 *string ` + "`gosb:\"required\"`" + `
+				F5, F6 []int   ` + "`gosb:\"optional\"`" + `
+			}`,
+			features: []labels.Feature{
+				labels.FeatureFlagPtr,
+			},
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "setter tags",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 string         ` + "`gosb:\"name=Host\"`" + `
+				F2 *int           ` + "`gosb:\"name=port\"`" + `
+				f3 int            ` + "`gosb:\"readonly,default=1\"`" + `
+				F4 []int          ` + "`gosb:\"-\"`" + `
+				f5 map[string]int ` + "`gosb:\"-\"`" + `
+			}
+
+			//go:generate gosb -source=input.go
+			type B struct {
+				F1 string ` + "`gosb:\"name=Name\"`" + `
+				F2 int    ` + "`gosb:\"readonly\"`" + `
+			}`,
+			features: []labels.Feature{
+				labels.FeatureFlagPtr,
+			},
+			mode: labels.ModeBuilder,
+			structSettings: map[string]Settings{
+				"B": {Features: nil, Mode: labels.ModeOptions},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "gosb builder directives",
+			source: `
+			package main
+
+			//gosb:builder mode=step setter="With{{ .Field }}"
+			type A struct {
+				F1 int
+			}
+
+			//gosb:builder
+			type B struct {
+				F2 *C
+			}
+
+			//gosb:builder features=ptr builder={{.Struct}}Factory build=Create
+			type C struct {
+				F3 *string
+			}`,
+			features: nil,
+			mode:     labels.ModeBuilder,
+			structSettings: map[string]Settings{
+				"A": {
+					Features: nil,
+					Mode:     labels.ModeOptions,
+					Naming: Naming{
+						Setter:        "",
+						FeatureSetter: "",
+						Builder:       "",
+						Constructor:   "",
+						Build:         "",
+						ToBuilder:     "",
+						Option:        "",
+					},
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "naming",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 *int
+				F2 B
+			}
+
+			//go:generate gosb -source=input.go
+			type B struct {
+				F3 string
+			}
+
+			//go:generate gosb -source=input.go
+			type c struct {
+				F4 int
+			}`,
+			features: []labels.Feature{
+				labels.FeatureFlagPtr,
+			},
+			mode: labels.ModeBuilder,
+			structSettings: map[string]Settings{
+				"A": {
+					Features: []labels.Feature{labels.FeatureFlagPtr},
+					Mode:     labels.ModeBuilder,
+					Naming: Naming{
+						Setter:        "{{.Field}}",
+						FeatureSetter: "{{.Setter}}Value",
+						Builder:       "{{.Struct}}Factory",
+						Constructor:   "Make{{.Builder}}",
+						Build:         "",
+						ToBuilder:     "",
+						Option:        "",
+					},
+				},
+				"B": {
+					Features: nil,
+					Mode:     labels.ModeBuilder,
+					Naming: Naming{
+						Setter:        "",
+						FeatureSetter: "",
+						Builder:       "",
+						Constructor:   "",
+						Build:         "Create",
+						ToBuilder:     "",
+						Option:        "",
+					},
+				},
+				"c": {
+					Features: nil,
+					Mode:     labels.ModeStep,
+					Naming: Naming{
+						Setter:        "With{{.Field}}",
+						FeatureSetter: "",
+						Builder:       "{{.Struct}}Factory",
+						Constructor:   "",
+						Build:         "",
+						ToBuilder:     "",
+						Option:        "",
+					},
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "naming of the nested builder method collision",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 B
+			}
+
+			//go:generate gosb -source=input.go
+			type B struct {
+				F2 string
+			}`,
+			features: nil,
+			mode:     labels.ModeBuilder,
+			structSettings: map[string]Settings{
+				"A": {
+					Features: nil,
+					Mode:     labels.ModeBuilder,
+					Naming: Naming{
+						Setter:        "With{{.Field}}",
+						FeatureSetter: "",
+						Builder:       "",
+						Constructor:   "",
+						Build:         "",
+						ToBuilder:     "",
+						Option:        "",
+					},
+				},
+			},
+			expectedErr: errors.New("method='WithF1' of the struct='A' builder is generated more than once"),
+		},
+		{
+			name: "naming of the builders collision",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 int
+			}
+
+			//go:generate gosb -source=input.go
+			type B struct {
+				F2 string
+			}`,
+			features: nil,
+			mode:     labels.ModeBuilder,
+			structSettings: map[string]Settings{
+				"B": {
+					Features: nil,
+					Mode:     labels.ModeBuilder,
+					Naming: Naming{
+						Setter:        "",
+						FeatureSetter: "",
+						Builder:       "ABuilder",
+						Constructor:   "",
+						Build:         "",
+						ToBuilder:     "",
+						Option:        "",
+					},
+				},
+			},
+			expectedErr: errors.New("name='ABuilder' of the struct='B' is already used by the struct='A'"),
+		},
+		{
+			name: "to builder naming",
+			source: `
+			package main
+
+			//gosb:builder to_builder=-
+			type A struct {
+				F1 int
+			}
+
+			//gosb:builder to_builder=Edit
+			type B struct {
+				F2 int
+			}`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "to builder method collision",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 int
+			}
+
+			func (a *A) ToBuilder() *A {
+				return a
+			}`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    errors.New("method='ToBuilder' generated for the struct='A' is already declared by it"),
+		},
+		{
+			name: "getter collision",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				f1 int
+				F1 int ` + "`gosb:\"readonly\"`" + `
+			}`,
+			features:       nil,
+			mode:           labels.ModeBuilder,
+			structSettings: nil,
+			expectedErr:    errors.New("method='F1' generated for the struct='A' is already declared by it"),
+		},
+		{
+			name: "naming pattern producing invalid name",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 int
+			}`,
+			features: nil,
+			mode:     labels.ModeBuilder,
+			structSettings: map[string]Settings{
+				"A": {
+					Features: nil,
+					Mode:     labels.ModeBuilder,
+					Naming: Naming{
+						Setter:        "Set-{{.Field}}",
+						FeatureSetter: "",
+						Builder:       "",
+						Constructor:   "",
+						Build:         "",
+						ToBuilder:     "",
+						Option:        "",
+					},
+				},
+			},
+			expectedErr: fmt.Errorf("naming the struct='%s': %w", "A",
+				errors.New("name='Set-F1' generated by the naming pattern='Set-{{.Field}}' is not an identifier")),
+		},
+		{
+			name: "step builder",
+			source: `
+			package main
+
+			import "github.com/samber/mo"
+
+			//go:generate gosb -source=input.go -features=ptr,arr,opt -mode=step
+			type A struct {
+				F1 int
+				F2 *string
+				F3 []int
+				F4 mo.Option[int]
+				f5 string
+			}`,
+			features: []labels.Feature{
+				labels.FeatureFlagPtr,
+				labels.FeatureFlagArr,
+				labels.FeatureFlagOpt,
+			},
+			mode:           labels.ModeStep,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
+		{
+			name: "step builder without required fields",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go -mode=step
+			type a[T any] struct {
+				F1 *T
+			}`,
+			features:       nil,
+			mode:           labels.ModeStep,
+			structSettings: nil,
+			expectedErr:    nil,
+		},
 	}
 
 	for _, c := range cases {
 		c := c
 		t.Run(c.name, func(t *testing.T) {
-			f, err := os.CreateTemp("", "")
-			require.NoError(t, err, "creating a temp file")
+			g := NewGenerator(Settings{Features: c.features, Mode: c.mode}, c.structSettings, "")
 
-			defer func() {
-				assert.NoError(t, f.Close(), "closing the temp file")
-				assert.NoError(t, os.Remove(f.Name()), "deleting the temp file")
-			}()
-
-			_, _ = f.WriteString(c.source)
-			_, _ = f.Seek(0, 0)
-
-			g := NewGenerator(c.features)
-
-			parsedFile, err := NewParser().Parse(f)
+			parsedFile, err := parseSource(t, t.TempDir(), c.source)
 			require.NoError(t, err)
 
-			parsedFile.Name = "input.go"
-
 			data, err := g.Generate(parsedFile)
 			if c.expectedErr != nil {
 				require.Equal(t, c.expectedErr, err)
@@ -265,3 +1013,103 @@ func TestGenerator_Generate(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerator_Generate_Type(t *testing.T) {
+	dir := t.TempDir()
+	writeSources(t, dir, typeSources)
+
+	parsedFile, err := NewParser().ParseType(context.Background(), filepath.Join(dir, "out"), "test/ext.Config")
+	require.NoError(t, err)
+
+	g := NewGenerator(Settings{Features: []labels.Feature{labels.FeatureFlagPtr}, Mode: labels.ModeBuilder}, nil, "")
+
+	data, err := g.Generate(parsedFile)
+	require.NoError(t, err)
+
+	actual := fmt.Sprintf("--- source code ---\n%s\n\n\n--- generated code ---\n\n%s", typeSources["ext/ext.go"], data)
+	expected := goldenFile(t, "type of another package", actual)
+	assert.Equal(t, expected, actual)
+}
+
+func TestGenerator_Template(t *testing.T) {
+	const source = `
+	package main
+
+	//go:generate gosb -source=input.go
+	type A struct {
+		F1 int
+	}`
+
+	cases := []struct {
+		name        string
+		template    string
+		expected    []string
+		expectedErr error
+	}{
+		{
+			name: "redefined templates",
+			template: `
+{{define "header"}}// Code generated by a custom template.
+
+package {{.File.Pkg}}
+{{end}}
+{{define "struct"}}{{template "builder" (structData .)}}
+// {{constructorName .}} creates the {{builderName .}} of the {{structType .}} in the {{mode .}} mode.
+{{end}}`,
+			expected: []string{
+				"// Code generated by a custom template.",
+				"// NewABuilder creates the ABuilder of the A in the builder mode.",
+				"func NewABuilder() *ABuilder {",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "redefined field template",
+			template: `
+{{define "header"}}package {{.File.Pkg}}
+{{end}}
+{{define "setter"}}// {{.Setter.Name}} sets the {{.Field.Name}} field of the {{.Struct.Type}}.
+func (b *{{.Struct.BuilderType}}) {{.Setter.Name}}({{.Setter.Param}}) *{{.Struct.BuilderType}} {
+	b.x.{{.Field.Name}} = {{.Setter.Value}}
+{{- template "markProvided" .Field}}
+	return b
+}
+{{end}}`,
+			expected: []string{
+				"// SetF1 sets the F1 field of the A.\nfunc (b *ABuilder) SetF1(v int) *ABuilder {",
+				"b.mask[1/8] &= ^uint8(1 << (1 % 8))",
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "invalid template",
+			template:    `{{define "struct"}}{{structData}}{{end}}`,
+			expected:    nil,
+			expectedErr: errors.New("executing template"),
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGenerator(Settings{Features: nil, Mode: labels.ModeBuilder}, nil, c.template)
+
+			parsedFile, err := parseSource(t, t.TempDir(), source)
+			require.NoError(t, err)
+
+			data, err := g.Generate(parsedFile)
+			if c.expectedErr != nil {
+				require.ErrorContains(t, err, c.expectedErr.Error())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), "DO NOT EDIT")
+
+			for _, s := range c.expected {
+				assert.Contains(t, string(data), s)
+			}
+		})
+	}
+}
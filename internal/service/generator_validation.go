@@ -0,0 +1,164 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/slavaavr/go-struct-builder/internal/labels"
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+// getRegexps returns the package level variables of the compiled regex validation rules.
+func getRegexps(st model.Struct) []RegexpData {
+	res := make([]RegexpData, 0)
+
+	for _, fld := range st.Fields {
+		for i, v := range fld.Validations {
+			if v.Rule == labels.ValidationRegex {
+				res = append(res, RegexpData{
+					Name:    getRegexpVarName(st, fld, i),
+					Pattern: quoteRegexp(v.Arg),
+				})
+			}
+		}
+	}
+
+	return res
+}
+
+// getValidations returns the checks of the validation rules of the field for the struct value stored in b.x.
+func getValidations(st model.Struct, fld model.Field) []ValidationData {
+	res := make([]ValidationData, 0, len(fld.Validations))
+
+	for i, v := range fld.Validations {
+		cond, msg := getValidationCheck(st, fld, i, v)
+
+		res = append(res, ValidationData{
+			Rule: v.Rule,
+			Cond: cond,
+			Msg:  msg,
+		})
+	}
+
+	return res
+}
+
+// getValidationCheck returns the condition failing the validation rule along with the description of the rule.
+func getValidationCheck(st model.Struct, fld model.Field, idx int, v model.Validation) (string, string) {
+	var (
+		target  = "b.x." + fld.Name
+		operand = target
+		subject = "must"
+	)
+
+	if isTypeKindWithLen(fld.Type.Kind) {
+		operand = fmt.Sprintf("len(%s)", target)
+		subject = "length must"
+	}
+
+	switch v.Rule {
+	case labels.ValidationMin:
+		return fmt.Sprintf("%s < %s", operand, v.Arg),
+			fmt.Sprintf("%s be greater than or equal to %s", subject, v.Arg)
+
+	case labels.ValidationMax:
+		return fmt.Sprintf("%s > %s", operand, v.Arg),
+			fmt.Sprintf("%s be less than or equal to %s", subject, v.Arg)
+
+	case labels.ValidationLen:
+		return fmt.Sprintf("%s != %s", operand, v.Arg),
+			fmt.Sprintf("%s be equal to %s", subject, v.Arg)
+
+	case labels.ValidationRegex:
+		if fld.Type.Name != "string" {
+			target = fmt.Sprintf("string(%s)", target)
+		}
+
+		return fmt.Sprintf("!%s.MatchString(%s)", getRegexpVarName(st, fld, idx), target),
+			"must match the regular expression " + v.Arg
+
+	case labels.ValidationOneOf:
+		values := strings.Fields(v.Arg)
+		conds := make([]string, 0, len(values))
+
+		for _, value := range values {
+			if fld.Type.Kind == model.TypeKindString {
+				value = strconv.Quote(value)
+			}
+
+			conds = append(conds, fmt.Sprintf("%s != %s", target, value))
+		}
+
+		return strings.Join(conds, " && "),
+			fmt.Sprintf("must be one of [%s]", strings.Join(values, " "))
+
+	default:
+		return fmt.Sprintf("%s == %s", target, getZeroValue(fld.Type)),
+			"must not be zero"
+	}
+}
+
+func getZeroValue(typ model.FieldType) string {
+	switch typ.Kind {
+	case model.TypeKindBool:
+		return "false"
+
+	case model.TypeKindInt, model.TypeKindFloat:
+		return "0"
+
+	case model.TypeKindString:
+		return `""`
+
+	case model.TypeKindStruct, model.TypeKindArray:
+		return fmt.Sprintf("(%s{})", typ.Name)
+
+	default:
+		return "nil"
+	}
+}
+
+func getRegexpVarName(st model.Struct, fld model.Field, idx int) string {
+	name := fmt.Sprintf("%s%sRegexp", makeStringLower(st.Name), makeStringCapital(fld.Name))
+
+	// a field may have several regex rules
+	for i, v := range fld.Validations {
+		if i < idx && v.Rule == labels.ValidationRegex {
+			return fmt.Sprintf("%s%d", name, idx)
+		}
+	}
+
+	return name
+}
+
+func quoteRegexp(s string) string {
+	if strings.Contains(s, "`") {
+		return strconv.Quote(s)
+	}
+
+	return "`" + s + "`"
+}
+
+func isStructHasValidation(st model.Struct) bool {
+	for _, fld := range st.Fields {
+		if len(fld.Validations) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isFileHasValidationRule(f *model.File, rule string) bool {
+	for _, st := range f.Structs {
+		for _, fld := range st.Fields {
+			for _, v := range fld.Validations {
+				if v.Rule == rule {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
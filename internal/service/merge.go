@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+// MergeFiles combines parsed files of the same package, so a single builder file can be generated for all of them.
+func MergeFiles(files []*model.File) (*model.File, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no files provided for merging")
+	}
+
+	var (
+		names   = make([]string, 0, len(files))
+		imports = make([]model.Import, 0)
+		structs = make([]model.Struct, 0)
+		others  []model.Struct
+		// seen are the names of the merged structs along with the others already added
+		seen = make(map[string]struct{})
+	)
+
+	for _, f := range files {
+		for _, st := range f.Structs {
+			seen[st.Name] = struct{}{}
+		}
+	}
+
+	for _, f := range files {
+		if f.Path != files[0].Path || f.Pkg != files[0].Pkg {
+			return nil, fmt.Errorf("file='%s' belongs to a different package than file='%s'", f.Name, files[0].Name)
+		}
+
+		names = append(names, f.Name)
+		structs = append(structs, f.Structs...)
+
+		// the others of a file include the structs of the rest of the merged files
+		for _, st := range f.Others {
+			if _, ok := seen[st.Name]; !ok {
+				seen[st.Name] = struct{}{}
+				others = append(others, st)
+			}
+		}
+
+		for _, imp := range f.Imports {
+			if !containsImport(imports, imp) {
+				imports = append(imports, imp)
+			}
+		}
+	}
+
+	return &model.File{
+		Name:    strings.Join(names, ", "),
+		Path:    files[0].Path,
+		Pkg:     files[0].Pkg,
+		Imports: imports,
+		Structs: structs,
+		Others:  others,
+		Decls:   files[0].Decls,
+	}, nil
+}
+
+func containsImport(imports []model.Import, imp model.Import) bool {
+	for _, tmp := range imports {
+		if tmp.Value != imp.Value {
+			continue
+		}
+
+		if (tmp.Alias == nil && imp.Alias == nil) ||
+			(tmp.Alias != nil && imp.Alias != nil && *tmp.Alias == *imp.Alias) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+func TestMergeFiles(t *testing.T) {
+	var (
+		structA = model.Struct{Name: "A", Private: false, Qualifier: "", TypeParams: nil, Directive: nil, Fields: nil}
+		structB = model.Struct{Name: "B", Private: false, Qualifier: "", TypeParams: nil, Directive: nil, Fields: nil}
+		structC = model.Struct{Name: "C", Private: false, Qualifier: "", TypeParams: nil, Directive: nil, Fields: nil}
+	)
+
+	cases := []struct {
+		name        string
+		files       []*model.File
+		expected    *model.File
+		expectedErr error
+	}{
+		{
+			name:        "no files",
+			files:       nil,
+			expected:    nil,
+			expectedErr: errors.New("no files provided for merging"),
+		},
+		{
+			name: "same package",
+			files: []*model.File{
+				{
+					Name: "a.go",
+					Path: "x",
+					Pkg:  "main",
+					Imports: []model.Import{
+						{Value: `"time"`, Alias: nil},
+						{Value: `"time"`, Alias: ptr("t1")},
+					},
+					Structs: []model.Struct{structA},
+					Others:  nil,
+					Decls:   nil,
+				},
+				{
+					Name: "b.go",
+					Path: "x",
+					Pkg:  "main",
+					Imports: []model.Import{
+						{Value: `"time"`, Alias: ptr("t1")},
+						{Value: `"github.com/samber/mo"`, Alias: nil},
+					},
+					Structs: []model.Struct{structB},
+					Others:  nil,
+					Decls:   nil,
+				},
+			},
+			expected: &model.File{
+				Name: "a.go, b.go",
+				Path: "x",
+				Pkg:  "main",
+				Imports: []model.Import{
+					{Value: `"time"`, Alias: nil},
+					{Value: `"time"`, Alias: ptr("t1")},
+					{Value: `"github.com/samber/mo"`, Alias: nil},
+				},
+				Structs: []model.Struct{structA, structB},
+				Others:  nil,
+				Decls:   nil,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "others and declarations of the package",
+			files: []*model.File{
+				{
+					Name:    "a.go",
+					Path:    "x",
+					Pkg:     "main",
+					Imports: nil,
+					Structs: []model.Struct{structA},
+					Others:  []model.Struct{structB, structC},
+					Decls:   []string{"A", "B", "C", "D"},
+				},
+				{
+					Name:    "b.go",
+					Path:    "x",
+					Pkg:     "main",
+					Imports: nil,
+					Structs: []model.Struct{structB},
+					Others:  []model.Struct{structA, structC},
+					Decls:   []string{"A", "B", "C", "D"},
+				},
+			},
+			expected: &model.File{
+				Name:    "a.go, b.go",
+				Path:    "x",
+				Pkg:     "main",
+				Imports: []model.Import{},
+				Structs: []model.Struct{structA, structB},
+				Others:  []model.Struct{structC},
+				Decls:   []string{"A", "B", "C", "D"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "different packages",
+			files: []*model.File{
+				{Name: "a.go", Path: "x", Pkg: "main", Imports: nil, Structs: nil},
+				{Name: "b.go", Path: "y", Pkg: "main", Imports: nil, Structs: nil},
+			},
+			expected:    nil,
+			expectedErr: fmt.Errorf("file='%s' belongs to a different package than file='%s'", "b.go", "a.go"),
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			actual, actualErr := MergeFiles(c.files)
+			require.Equal(t, c.expectedErr, actualErr, "errors are not equal")
+			assert.Equal(t, c.expected, actual, "values are not equal")
+		})
+	}
+}
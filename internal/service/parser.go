@@ -1,112 +1,674 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/build"
 	goparser "go/parser"
 	gotoken "go/token"
 	gotypes "go/types"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	"golang.org/x/tools/go/packages"
+
 	"github.com/slavaavr/go-struct-builder/internal/labels"
 	"github.com/slavaavr/go-struct-builder/internal/model"
 )
 
 type Parser interface {
-	Parse(f *os.File) (*model.File, error)
+	// Parse parses the source file read from the reader, the filename is the logical path of the file,
+	// which is not required to exist on the disk, e.g. for an unsaved buffer of an editor.
+	Parse(ctx context.Context, filename string, r io.Reader) (*model.File, error)
+	// ParsePackages parses every file of the packages matched by the patterns, e.g. ./...,
+	// and returns only the files containing structs the builders must be generated for.
+	ParsePackages(ctx context.Context, dir string, patterns ...string) ([]*model.File, error)
+	// ParseSources parses in-memory source files of a single package keyed by the file names,
+	// which are relative to the dir the imports are resolved in.
+	ParseSources(ctx context.Context, dir string, sources map[string][]byte) ([]*model.File, error)
+	// ParseFS parses the Go source files in the root of the file system as the package of the dir.
+	ParseFS(ctx context.Context, dir string, fsys fs.FS) ([]*model.File, error)
+	// ParseType parses the struct type declared by another package, e.g. net/http.Server,
+	// the builder of which is generated into the package of the dir.
+	ParseType(ctx context.Context, dir, typeName string) (*model.File, error)
+}
+
+// OutputPackage is the package the builders are generated into.
+type OutputPackage struct {
+	// Dir is the absolute path of the package directory, the directory of the sources is used if it's empty
+	Dir string
+	// Name is the name of the package, by default it's the name of the sources package
+	// for the directory of the sources, and the name of the directory otherwise
+	Name string
+	// Resolve returns the package the builders of the sources in the dir are generated into if the Dir is empty,
+	// e.g. the one set by the config of the dir, the empty Dir of the package stands for the sources directory
+	Resolve func(dir string) (OutputPackage, error)
 }
 
 type parser struct {
-	commentLabels []string
+	output OutputPackage
 }
 
+// NewParser creates a parser of the structs the builders are generated for into the package of the sources.
 func NewParser() Parser {
+	return NewOutputParser(OutputPackage{
+		Dir:     "",
+		Name:    "",
+		Resolve: nil,
+	})
+}
+
+// NewOutputParser creates a parser of the structs the builders are generated for into the output package.
+// Types of the sources package are qualified unless it's the output package, and the structs
+// which can't be built outside of their package are reported.
+func NewOutputParser(output OutputPackage) Parser {
 	return &parser{
-		commentLabels: []string{labels.Gosb, labels.GenerateCmd},
+		output: output,
 	}
 }
 
-func (s *parser) Parse(f *os.File) (*model.File, error) {
-	filename := f.Name()
-	fileSet := gotoken.NewFileSet()
+func (s *parser) Parse(ctx context.Context, filename string, r io.Reader) (*model.File, error) {
+	filename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute path of the file='%v': %w", filename, err)
+	}
 
-	file, err := goparser.ParseFile(fileSet, "", f, goparser.ParseComments)
+	src, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("parsing source file='%v': %w", filename, err)
+		return nil, fmt.Errorf("reading source file='%v': %w", filename, err)
+	}
+
+	pkg, file, err := s.loadPackage(ctx, filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.parseFile(pkg, filename, file)
+}
+
+func (s *parser) ParsePackages(ctx context.Context, dir string, patterns ...string) ([]*model.File, error) {
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packagesLoadMode,
+		Dir:     dir,
+		Fset:    gotoken.NewFileSet(),
+	}
+
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("loading packages %v: %w", patterns, err)
+	}
+
+	return s.parsePackages(pkgs)
+}
+
+func (s *parser) ParseSources(ctx context.Context, dir string, sources map[string][]byte) ([]*model.File, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("no sources provided")
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute path of the directory='%v': %w", dir, err)
 	}
 
 	var (
-		imports = make([]model.Import, 0)
-		structs = make([]model.Struct, 0)
+		filenames = make([]string, 0, len(sources))
+		overlay   = make(map[string][]byte, len(sources))
 	)
 
+	for name, src := range sources {
+		filename := filepath.Join(dir, name)
+		if filepath.Dir(filename) != dir {
+			return nil, fmt.Errorf("source file='%v' must be in the directory='%v'", name, dir)
+		}
+
+		filenames = append(filenames, filename)
+		overlay[filename] = src
+	}
+
+	sort.Strings(filenames)
+
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packagesLoadMode,
+		Dir:     getExistingDir(dir),
+		Fset:    gotoken.NewFileSet(),
+		Overlay: overlay,
+	}
+
+	// the listed files make up a single package regardless of the files on the disk
+	pkgs, err := packages.Load(cfg, filenames...)
+	if err != nil {
+		return nil, fmt.Errorf("loading sources: %w", err)
+	}
+
+	return s.parsePackages(pkgs)
+}
+
+func (s *parser) ParseFS(ctx context.Context, dir string, fsys fs.FS) ([]*model.File, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("reading file system: %w", err)
+	}
+
+	sources := make(map[string][]byte)
+
+	for _, e := range entries {
+		name := e.Name()
+
+		if e.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		if sources[name], err = fs.ReadFile(fsys, name); err != nil {
+			return nil, fmt.Errorf("reading source file='%v': %w", name, err)
+		}
+	}
+
+	return s.ParseSources(ctx, dir, sources)
+}
+
+// getExistingDir returns the closest existing directory, the sources may belong to a directory
+// which is not created yet, while the go command must be run in an existing one.
+func getExistingDir(dir string) string {
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+
+		dir = parent
+	}
+}
+
+func (s *parser) parsePackages(pkgs []*packages.Package) ([]*model.File, error) {
+	res := make([]*model.File, 0)
+
+	for _, pkg := range pkgs {
+		if err := checkPackageErrors(pkg); err != nil {
+			return nil, err
+		}
+
+		for i, file := range pkg.Syntax {
+			filename := pkg.CompiledGoFiles[i]
+
+			parsedFile, err := s.parseFile(pkg, filename, file)
+			if err != nil {
+				return nil, fmt.Errorf("parsing source file='%v': %w", filename, err)
+			}
+
+			if len(parsedFile.Structs) > 0 {
+				res = append(res, parsedFile)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func (s *parser) parseFile(pkg *packages.Package, filename string, file *ast.File) (*model.File, error) {
+	var (
+		builders    = s.findBuilderDecls(pkg)
+		generated   = findGeneratedFiles(pkg)
+		resolver    = newTypeResolver(pkg.Fset, pkg.Types, pkg.TypesInfo, file, builders)
+		structs     = make([]model.Struct, 0)
+		unbuildable = make([]string, 0)
+	)
+
+	target, err := s.getTargetPackage(pkg, filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	if target != nil {
+		resolver.target = target
+	}
+
 	for _, decl := range file.Decls {
 		gd, ok := decl.(*ast.GenDecl)
-		if !ok {
+		if !ok || gd.Tok != gotoken.TYPE {
 			continue
 		}
 
-		if gd.Tok == gotoken.IMPORT {
-			imports = append(imports, s.parseImports(gd)...)
-		} else if gd.Tok == gotoken.TYPE {
-			if s.containCommentLabels(gd) {
-				res, err := s.parseStruct(gd)
-				if err != nil {
-					return nil, fmt.Errorf("parsing struct: %w", err)
-				}
+		specs, err := s.findStructSpecs(pkg.Fset, gd)
+		if err != nil {
+			return nil, fmt.Errorf("parsing struct: %w", err)
+		}
+
+		for _, spec := range specs {
+			res, err := s.parseStruct(resolver, spec)
+			if err != nil {
+				return nil, fmt.Errorf("parsing struct: %w", err)
+			}
+
+			res.Methods = getStructMethods(pkg, spec, generated)
 
-				structs = append(structs, *res)
+			if reasons := checkOutsideStruct(resolver, spec, res); len(reasons) > 0 {
+				unbuildable = append(unbuildable, fmt.Sprintf("%s (%s)", res.Name, strings.Join(reasons, ", ")))
 			}
+
+			structs = append(structs, *res)
+		}
+	}
+
+	if len(unbuildable) > 0 {
+		return nil, fmt.Errorf("structs can't be built outside the package='%s': %s",
+			pkg.PkgPath, strings.Join(unbuildable, "; "))
+	}
+
+	if isStructsHaveNestedField(structs) {
+		s.setNestedBuilders(pkg, builders, structs)
+	}
+
+	pkgName := file.Name.Name
+	decls := getPackageDecls(pkg, generated)
+
+	if resolver.isOutside() {
+		pkgName = resolver.target.Name()
+
+		if decls, err = getDirDecls(resolver.target.Path(), pkgName); err != nil {
+			return nil, err
 		}
 	}
 
 	return &model.File{
 		Name:    filepath.Base(filename),
 		Path:    filepath.Dir(filename),
-		Pkg:     file.Name.Name,
-		Imports: s.updateImports(structs, imports),
+		Pkg:     pkgName,
+		Imports: resolver.usedImports(),
 		Structs: structs,
+		Others:  s.parseOtherStructs(pkg, file, builders),
+		Decls:   decls,
 	}, nil
 }
 
-func (s *parser) updateImports(structs []model.Struct, imports []model.Import) []model.Import {
-	newImports := make([]model.Import, 0, len(imports))
+// parseOtherStructs returns the annotated structs of the other files of the package,
+// their errors are reported when the builders of these files are generated.
+func (s *parser) parseOtherStructs(
+	pkg *packages.Package,
+	file *ast.File,
+	builders map[*gotypes.TypeName]builderDecl,
+) []model.Struct {
+	var res []model.Struct
+
+	for _, other := range pkg.Syntax {
+		if other == file {
+			continue
+		}
+
+		resolver := newTypeResolver(pkg.Fset, pkg.Types, pkg.TypesInfo, other, builders)
+
+		for _, decl := range other.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != gotoken.TYPE {
+				continue
+			}
+
+			specs, _ := s.findStructSpecs(pkg.Fset, gd)
+
+			for _, spec := range specs {
+				if st, err := s.parseStruct(resolver, spec); err == nil {
+					res = append(res, *st)
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+// getTargetPackage returns the output package the builders of the sources in the dir are generated into,
+// nil if it's the package of the sources. Only the external test package can share the sources directory.
+func (s *parser) getTargetPackage(pkg *packages.Package, dir string) (*gotypes.Package, error) {
+	output := s.output
+
+	if output.Dir == "" && output.Resolve != nil {
+		resolved, err := output.Resolve(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		output.Dir = resolved.Dir
+
+		if resolved.Name != "" {
+			output.Name = resolved.Name
+		}
+	}
+
+	var (
+		outDir  = dir
+		outName = output.Name
+	)
+
+	if output.Dir != "" {
+		outDir = output.Dir
+	}
+
+	if outName == "" {
+		outName = pkg.Name
+		if outDir != dir {
+			outName = filepath.Base(outDir)
+		}
+	}
+
+	if outDir == dir && outName == pkg.Name {
+		return nil, nil
+	}
+
+	if outDir == dir && outName != pkg.Name+"_test" {
+		return nil, fmt.Errorf("output package name='%s' differs from the package='%s' of the sources directory",
+			outName, pkg.Name)
+	}
+
+	return gotypes.NewPackage(outDir, outName), nil
+}
+
+// checkOutsideStruct returns the reasons the struct can't be built by the builder generated outside its package.
+func checkOutsideStruct(resolver *typeResolver, spec structSpec, st *model.Struct) []string {
+	if !resolver.isOutside() {
+		return nil
+	}
+
+	res := make([]string, 0)
+
+	if st.Private {
+		res = append(res, "unexported struct")
+	}
+
+	fieldTypes := make(map[string]ast.Expr)
+
+	for _, f := range spec.spec.Type.(*ast.StructType).Fields.List { // nolint: forcetypeassert
+		for _, name := range getFieldNames(f) {
+			fieldTypes[name] = f.Type
+		}
+	}
+
+	for _, fld := range st.Fields {
+		if fld.Private {
+			res = append(res, fmt.Sprintf("unexported field='%s'", fld.Name))
+
+			continue
+		}
+
+		if name := resolver.unexportedType(fieldTypes[fld.Name]); name != "" {
+			res = append(res, fmt.Sprintf("field='%s' of unexported type='%s'", fld.Name, name))
+		}
+	}
+
+	return res
+}
+
+// findGeneratedFiles returns the names of the generated files of the package, the ones having
+// the standard "// Code generated ... DO NOT EDIT." comment, including the files of gosb with a custom header.
+// Their declarations are regenerated along with the builders, so they don't collide with the generated names.
+func findGeneratedFiles(pkg *packages.Package) map[string]struct{} {
+	res := make(map[string]struct{})
+
+	for _, file := range pkg.Syntax {
+		if ast.IsGenerated(file) {
+			res[pkg.Fset.Position(file.Package).Filename] = struct{}{}
+		}
+	}
+
+	return res
+}
+
+// getPackageDecls returns the names of the package level declarations of the package,
+// except the ones of the generated files.
+func getPackageDecls(pkg *packages.Package, generated map[string]struct{}) []string {
+	if pkg.Types == nil {
+		return nil
+	}
+
+	scope := pkg.Types.Scope()
+	res := make([]string, 0, scope.Len())
+
+	for _, name := range scope.Names() {
+		if _, ok := generated[pkg.Fset.Position(scope.Lookup(name).Pos()).Filename]; !ok {
+			res = append(res, name)
+		}
+	}
+
+	return res
+}
+
+// getDirDecls returns the names of the package level declarations of the files of the package declared
+// in the dir, except the ones of the generated files. It's used for the output package, which isn't loaded
+// along with the sources, so its files are only parsed.
+func getDirDecls(dir, pkgName string) ([]string, error) {
+	files, err := parseDirFiles(dir, pkgName)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0)
+
+	for _, f := range files {
+		if !ast.IsGenerated(f.file) {
+			res = append(res, getFileDecls(f.file)...)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// dirFile is a parsed Go file of a directory along with its path.
+type dirFile struct {
+	path string
+	file *ast.File
+}
+
+// parseDirFiles parses the Go files of the package declared in the dir, which are matched
+// by the build constraints of the current platform.
+func parseDirFiles(dir, pkgName string) ([]dirFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading output directory='%s': %w", dir, err)
+	}
+
+	var (
+		fset = gotoken.NewFileSet()
+		res  = make([]dirFile, 0)
+	)
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		// the files of an external test package are the test files only, and the ones of the package aren't
+		if entry.IsDir() || strings.HasSuffix(name, "_test.go") != strings.HasSuffix(pkgName, "_test") {
+			continue
+		}
+
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+
+		filename := filepath.Join(dir, name)
+
+		file, err := goparser.ParseFile(fset, filename, nil, goparser.ParseComments|goparser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("parsing output package file: %w", err)
+		}
+
+		if file.Name.Name == pkgName {
+			res = append(res, dirFile{
+				path: filename,
+				file: file,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// getFileDecls returns the names of the package level declarations of the file, except the methods.
+func getFileDecls(file *ast.File) []string {
+	var res []string
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.Name != "init" {
+				res = append(res, d.Name.Name)
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					res = append(res, sp.Name.Name)
+
+				case *ast.ValueSpec:
+					for _, name := range sp.Names {
+						if name.Name != "_" {
+							res = append(res, name.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+// getStructMethods returns the names of the methods declared for the struct except the generated ones.
+func getStructMethods(pkg *packages.Package, spec structSpec, generated map[string]struct{}) []string {
+	if pkg.TypesInfo == nil {
+		return nil
+	}
+
+	obj, ok := pkg.TypesInfo.Defs[spec.spec.Name].(*gotypes.TypeName)
+	if !ok {
+		return nil
+	}
+
+	named, ok := obj.Type().(*gotypes.Named)
+	if !ok {
+		return nil
+	}
+
+	var res []string
+
+	for i := 0; i < named.NumMethods(); i++ {
+		method := named.Method(i)
+
+		if _, ok := generated[pkg.Fset.Position(method.Pos()).Filename]; !ok {
+			res = append(res, method.Name())
+		}
+	}
+
+	return res
+}
+
+// builderDecl is a declaration of a struct the builder is generated for.
+type builderDecl struct {
+	file *ast.File
+	spec structSpec
+}
+
+// findBuilderDecls returns the non-generic structs of the package the builders are generated for,
+// fields of these struct types get methods setting them by the nested builders.
+func (s *parser) findBuilderDecls(pkg *packages.Package) map[*gotypes.TypeName]builderDecl {
+	res := make(map[*gotypes.TypeName]builderDecl)
+
+	if pkg.TypesInfo == nil {
+		return res
+	}
+
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != gotoken.TYPE {
+				continue
+			}
 
-	for _, imp := range imports {
-		if s.isImportUsed(imp, structs) {
-			newImports = append(newImports, imp)
+			// the errors are reported when the builders of the file are generated
+			specs, _ := s.findStructSpecs(pkg.Fset, gd)
+
+			for _, spec := range specs {
+				if obj, ok := pkg.TypesInfo.Defs[spec.spec.Name].(*gotypes.TypeName); ok && spec.spec.TypeParams == nil {
+					res[obj] = builderDecl{file: file, spec: spec}
+				}
+			}
 		}
 	}
 
-	return newImports
+	return res
 }
 
-func (s *parser) isImportUsed(imp model.Import, structs []model.Struct) bool {
-	var pkgPrefix string
+// setNestedBuilders describes the nested builders of the struct fields by the structs they build,
+// so the generator can tell whether their Build() methods return an error by the modes they're generated by.
+func (s *parser) setNestedBuilders(
+	pkg *packages.Package,
+	builders map[*gotypes.TypeName]builderDecl,
+	structs []model.Struct,
+) {
+	var (
+		nested = make(map[string]*model.Nested, len(builders))
+		parsed = make([]model.Struct, 0, len(builders))
+	)
+
+	for obj, b := range builders {
+		resolver := newTypeResolver(pkg.Fset, pkg.Types, pkg.TypesInfo, b.file, builders)
 
-	if imp.Alias != nil {
-		pkgPrefix = *imp.Alias
-	} else {
-		value := strings.Trim(imp.Value, "\"")
-		idx := strings.LastIndexByte(value, '/')
-		if idx != -1 {
-			pkgPrefix = value[idx+1:]
-		} else {
-			pkgPrefix = value
+		st, err := s.parseStruct(resolver, b.spec)
+		if err != nil {
+			// the error is reported when the builder of the struct is generated
+			continue
+		}
+
+		parsed = append(parsed, *st)
+		nested[st.Name] = &model.Nested{
+			Struct:    st.Name,
+			Private:   !obj.Exported(),
+			Required:  isStructHasRequiredField(*st),
+			Validated: isStructHasValidation(*st),
+			Nested:    nil,
+			Directive: b.spec.directive,
 		}
 	}
 
-	pkgPrefix += "."
+	for _, st := range parsed {
+		for _, fld := range st.Fields {
+			if fld.Nested != nil && nested[fld.Nested.Struct] != nil {
+				nested[st.Name].Nested = append(nested[st.Name].Nested, nested[fld.Nested.Struct])
+			}
+		}
+	}
 
+	for i := range structs {
+		for j, fld := range structs[i].Fields {
+			if fld.Nested != nil && nested[fld.Nested.Struct] != nil {
+				structs[i].Fields[j].Nested = nested[fld.Nested.Struct]
+			}
+		}
+	}
+}
+
+func isStructsHaveNestedField(structs []model.Struct) bool {
 	for _, st := range structs {
 		for _, fld := range st.Fields {
-			if strings.Contains(fld.Type.Name, pkgPrefix) {
+			if fld.Nested != nil {
 				return true
 			}
 		}
@@ -115,159 +677,470 @@ func (s *parser) isImportUsed(imp model.Import, structs []model.Struct) bool {
 	return false
 }
 
-func (s *parser) parseStruct(decl *ast.GenDecl) (*model.Struct, error) {
+// packagesLoadMode type-checks the dependencies from the sources as well, so the export data
+// of a Go toolchain newer than the one of golang.org/x/tools isn't read.
+const packagesLoadMode = packages.NeedName |
+	packages.NeedFiles |
+	packages.NeedCompiledGoFiles |
+	packages.NeedSyntax |
+	packages.NeedTypes |
+	packages.NeedTypesInfo |
+	packages.NeedImports |
+	packages.NeedDeps
+
+// loadPackage loads the package containing the file with full type information.
+// Type errors are tolerated, e.g. a stale builder file must not prevent its regeneration.
+func (s *parser) loadPackage(
+	ctx context.Context,
+	filename string,
+	src []byte,
+) (*packages.Package, *ast.File, error) {
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packagesLoadMode,
+		Dir:     getExistingDir(filepath.Dir(filename)),
+		Fset:    gotoken.NewFileSet(),
+		Overlay: map[string][]byte{filename: src},
+	}
+
+	pkgs, err := packages.Load(cfg, "file="+filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading package of the file='%v': %w", filename, err)
+	}
+
+	for _, pkg := range pkgs {
+		if err := checkPackageErrors(pkg); err != nil {
+			return nil, nil, fmt.Errorf("parsing source file='%v': %w", filename, err)
+		}
+
+		for i, name := range pkg.CompiledGoFiles {
+			if name == filename && i < len(pkg.Syntax) {
+				return pkg, pkg.Syntax[i], nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("package of the file='%v' not found", filename)
+}
+
+// checkPackageErrors reports syntax errors and packages that could not be loaded at all.
+func checkPackageErrors(pkg *packages.Package) error {
+	for _, e := range pkg.Errors {
+		if e.Kind == packages.ParseError || len(pkg.Syntax) == 0 {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// structSpec is a struct spec the builder is generated for along with the options of its gosb:builder directive.
+type structSpec struct {
+	spec      *ast.TypeSpec
+	directive map[string]string
+}
+
+// findStructSpecs returns the struct specs of the type declaration the builders are generated for.
+// A spec is annotated by its own doc comment or by the doc comment of the declaration,
+// which applies to every struct of a grouped declaration. The directive options of the spec
+// take precedence over the ones of the declaration.
+func (s *parser) findStructSpecs(fset *gotoken.FileSet, decl *ast.GenDecl) ([]structSpec, error) {
+	var (
+		res       = make([]structSpec, 0)
+		notStruct *ast.TypeSpec
+	)
+
+	annotation, declDirective, err := parseDirectives(fset, decl.Doc)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, spec := range decl.Specs {
 		ts, ok := spec.(*ast.TypeSpec)
 		if !ok {
 			continue
 		}
 
-		typ, ok := ts.Type.(*ast.StructType)
-		if !ok {
+		specAnnotation, directive, err := parseDirectives(fset, ts.Doc)
+		if err != nil {
+			return nil, err
+		}
+
+		if annotation == "" && specAnnotation == "" {
 			continue
 		}
 
-		structName := ts.Name.Name
-		fields := make([]model.Field, 0)
+		if _, ok = ts.Type.(*ast.StructType); !ok {
+			if specAnnotation != "" {
+				return nil, newNotStructError(fset, ts, specAnnotation)
+			}
 
-		if typ.Fields != nil {
-			for _, f := range typ.Fields.List {
-				field, err := s.parseField(f)
-				if err != nil {
-					return nil, fmt.Errorf("parsing %s.%s field", structName, f.Names[0].Name)
-				}
+			if notStruct == nil {
+				notStruct = ts
+			}
+
+			continue
+		}
+
+		res = append(res, structSpec{
+			spec:      ts,
+			directive: mergeDirectives(declDirective, directive),
+		})
+	}
+
+	if annotation != "" && len(res) == 0 {
+		if notStruct != nil {
+			return nil, newNotStructError(fset, notStruct, annotation)
+		}
+
+		return nil, fmt.Errorf("%s: no struct annotated by %s", fset.Position(decl.Pos()), annotation)
+	}
+
+	return res, nil
+}
+
+func newNotStructError(fset *gotoken.FileSet, ts *ast.TypeSpec, annotation string) error {
+	return fmt.Errorf("%s: type='%s' annotated by %s is not a struct", fset.Position(ts.Pos()), ts.Name.Name, annotation)
+}
 
-				fields = append(fields, *field)
+// parseDirectives returns the directive annotating a struct by the doc comment, either gosb:builder
+// or go:generate, empty if there is none, along with the options of the gosb:builder directives.
+func parseDirectives(fset *gotoken.FileSet, doc *ast.CommentGroup) (string, map[string]string, error) {
+	if doc == nil {
+		return "", nil, nil
+	}
+
+	var (
+		annotation string
+		res        map[string]string
+	)
+
+	for _, comment := range doc.List {
+		switch {
+		case labels.IsBuilderDirective(comment.Text):
+			directive, err := labels.ParseDirective(comment.Text)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: %w", fset.Position(comment.Pos()), err)
 			}
+
+			annotation = strings.TrimPrefix(labels.DirectiveBuilder, "//")
+			res = mergeDirectives(res, directive)
+
+		case labels.IsGenerateDirective(comment.Text) && annotation == "":
+			annotation = strings.TrimPrefix(labels.DirectiveGenerate, "//")
 		}
+	}
+
+	return annotation, res, nil
+}
 
-		return &model.Struct{
-			Name:    structName,
-			Private: !isStringCapital(structName),
-			Fields:  fields,
-		}, nil
+// mergeDirectives returns the options of both directives, the second one takes precedence.
+func mergeDirectives(d1, d2 map[string]string) map[string]string {
+	if len(d1) == 0 {
+		return d2
 	}
 
-	return nil, errors.New("struct not found")
+	if len(d2) == 0 {
+		return d1
+	}
+
+	res := make(map[string]string, len(d1)+len(d2))
+
+	for k, v := range d1 {
+		res[k] = v
+	}
+
+	for k, v := range d2 {
+		res[k] = v
+	}
+
+	return res
 }
 
-const (
-	moOptionType = "mo.Option"
-)
+func (s *parser) parseStruct(resolver *typeResolver, spec structSpec) (*model.Struct, error) {
+	var (
+		ts         = spec.spec
+		structName = ts.Name.Name
+		fields     = make([]model.Field, 0)
+		typ        = ts.Type.(*ast.StructType) // nolint: forcetypeassert
+	)
 
-func (s *parser) parseField(f *ast.Field) (*model.Field, error) {
-	fieldType := gotypes.ExprString(f.Type)
-	typeInfo := model.TypeInfoOther
+	if typ.Fields != nil {
+		for _, f := range typ.Fields.List {
+			parsedFields, err := s.parseField(resolver, f)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s.%s field: %w", structName, getFieldName(f), err)
+			}
 
-	switch {
-	case fieldType[0] == '*':
-		typeInfo = model.TypeInfoPointer
+			fields = append(fields, parsedFields...)
+		}
+	}
 
-	case strings.HasPrefix(fieldType, "[]"):
-		typeInfo = model.TypeInfoArray
+	return &model.Struct{
+		Name:       structName,
+		Private:    !isStringCapital(structName),
+		Qualifier:  resolver.structQualifier(),
+		TypeParams: s.parseTypeParams(resolver, ts.TypeParams),
+		Directive:  spec.directive,
+		Methods:    nil,
+		NoCopy:     resolver.noCopy(ts.Name),
+		Fields:     fields,
+	}, nil
+}
+
+func (s *parser) parseTypeParams(resolver *typeResolver, params *ast.FieldList) []model.TypeParam {
+	if params == nil {
+		return nil
+	}
 
-	case strings.HasPrefix(fieldType, moOptionType):
-		typeInfo = model.TypeInfoOption
+	res := make([]model.TypeParam, 0, params.NumFields())
+
+	for _, f := range params.List {
+		constraint := resolver.typeName(f.Type)
+
+		for _, name := range f.Names {
+			res = append(res, model.TypeParam{
+				Name:       name.Name,
+				Constraint: constraint,
+			})
+		}
 	}
 
+	return res
+}
+
+// parseField returns a field for every name of the field declaration, e.g. F1, F2 int,
+// or none if the field is skipped by the gosb:"-" tag.
+func (s *parser) parseField(resolver *typeResolver, f *ast.Field) ([]model.Field, error) {
+	fieldType := resolver.fieldType(f.Type)
 	required := true
 
-	if typeInfo == model.TypeInfoPointer || typeInfo == model.TypeInfoOption {
+	if fieldType.Info == model.TypeInfoPointer || fieldType.Info == model.TypeInfoOption {
 		required = false
 	}
 
-	var fieldTag string
+	var fieldTag labels.FieldTag
 
 	if f.Tag != nil {
-		value, err := strconv.Unquote(f.Tag.Value)
-		if err != nil {
-			return nil, fmt.Errorf("unquote field tag")
+		var err error
+
+		if fieldTag, err = parseFieldTag(f.Tag); err != nil {
+			return nil, fmt.Errorf("%s: %w", resolver.position(f.Tag.Pos()), err)
 		}
+	}
 
-		fieldTag = reflect.StructTag(value).Get(labels.Gosb)
+	if fieldTag.Skip {
+		return nil, nil
 	}
 
-	switch fieldTag {
-	case labels.StructTagRequired:
+	switch {
+	case fieldTag.Required:
 		required = true
 
-	case labels.StructTagOptional:
+	case fieldTag.Optional, fieldTag.Readonly, fieldTag.Default != "":
 		required = false
 	}
 
-	fieldName := s.getFieldName(f.Names, fieldType)
+	if fieldTag.Default != "" {
+		if err := resolver.checkDefault(fieldTag.Default, f.Type); err != nil {
+			return nil, fmt.Errorf("%s: %w", resolver.position(f.Tag.Pos()), err)
+		}
 
-	return &model.Field{
-		Name: fieldName,
-		Type: model.FieldType{
-			Name: fieldType,
-			Info: typeInfo,
-		},
-		Private:  !isStringCapital(fieldName),
-		Required: required,
-	}, nil
+		value, err := resolver.qualifyExpr(fieldTag.Default)
+		if err != nil {
+			return nil, fmt.Errorf("%s: qualifying default value: %w", resolver.position(f.Tag.Pos()), err)
+		}
+
+		fieldTag.Default = value
+	}
+
+	for _, v := range fieldTag.Validations {
+		if err := s.checkValidation(resolver, f.Type, fieldType, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", resolver.position(f.Tag.Pos()), err)
+		}
+	}
+
+	fieldNames := getFieldNames(f)
+
+	if fieldTag.Name != "" && len(fieldNames) > 1 {
+		return nil, fmt.Errorf("%s: tag option='%s' can't be used by the declaration of several fields",
+			resolver.position(f.Tag.Pos()), labels.StructTagName)
+	}
+
+	res := make([]model.Field, 0, len(fieldNames))
+
+	for _, fieldName := range fieldNames {
+		res = append(res, model.Field{
+			Name:        fieldName,
+			Type:        fieldType,
+			Private:     !isStringCapital(fieldName),
+			Required:    required,
+			Default:     fieldTag.Default,
+			Validations: fieldTag.Validations,
+			Nested:      resolver.nested(f.Type),
+			Alias:       fieldTag.Name,
+			Readonly:    fieldTag.Readonly,
+		})
+	}
+
+	return res, nil
 }
 
-var fieldNameFromTypeRegexp = regexp.MustCompile(`^\*?(?:\w+\.)?(\w+)`)
+// parseFieldTag parses the gosb key of the struct tag literal.
+func parseFieldTag(tag *ast.BasicLit) (labels.FieldTag, error) {
+	value, err := strconv.Unquote(tag.Value)
+	if err != nil {
+		return labels.FieldTag{}, fmt.Errorf("unquote field tag")
+	}
 
-func (s *parser) getFieldName(names []*ast.Ident, typ string) string {
-	var fieldName string
+	gosbTag, ok := reflect.StructTag(value).Lookup(labels.Gosb)
+	if !ok {
+		// a key which isn't followed by a quoted value is skipped by the lookup
+		if strings.Contains(value, labels.Gosb+":") {
+			return labels.FieldTag{}, fmt.Errorf("malformed struct tag='%s'", value)
+		}
+
+		return labels.FieldTag{}, nil
+	}
 
-	if names == nil {
-		// embedded field
-		fieldName = fieldNameFromTypeRegexp.FindStringSubmatch(typ)[1]
-	} else {
-		fieldName = names[0].Name
+	res, err := labels.ParseFieldTag(gosbTag)
+	if err != nil {
+		return labels.FieldTag{}, fmt.Errorf("parsing field tag: %w", err)
 	}
 
-	return fieldName
+	return res, nil
 }
 
-func (s *parser) parseImports(decl *ast.GenDecl) []model.Import {
-	imports := make([]model.Import, 0)
+// checkValidation reports validation rules which are not applicable to the field type.
+func (s *parser) checkValidation(
+	resolver *typeResolver,
+	expr ast.Expr,
+	typ model.FieldType,
+	v model.Validation,
+) error {
+	var (
+		hasLen     = isTypeKindWithLen(typ.Kind)
+		applicable bool
+	)
 
-	for _, spec := range decl.Specs {
-		impspec, ok := spec.(*ast.ImportSpec)
-		if !ok {
-			continue
+	switch v.Rule {
+	case labels.ValidationMin, labels.ValidationMax:
+		applicable = typ.Kind == model.TypeKindFloat || typ.Kind == model.TypeKindInt || hasLen
+
+		if applicable && typ.Kind != model.TypeKindFloat {
+			if _, err := strconv.ParseInt(v.Arg, 10, 64); err != nil {
+				return fmt.Errorf("value='%s' of the validation rule='%s' is not an integer", v.Arg, v.Rule)
+			}
 		}
 
-		var alias *string
-		if impspec.Name != nil {
-			alias = &impspec.Name.Name
+		if applicable && !hasLen && !resolver.isRepresentable(v.Arg, expr) {
+			return fmt.Errorf("value='%s' of the validation rule='%s' overflows the field type='%s'",
+				v.Arg, v.Rule, typ.Name)
 		}
 
-		imports = append(imports, model.Import{
-			Value: impspec.Path.Value,
-			Alias: alias,
-		})
+	case labels.ValidationLen:
+		applicable = hasLen
+
+	case labels.ValidationRegex:
+		applicable = typ.Kind == model.TypeKindString
+
+	case labels.ValidationOneOf:
+		applicable = typ.Kind == model.TypeKindString || typ.Kind == model.TypeKindInt || typ.Kind == model.TypeKindFloat
+
+		for _, value := range strings.Fields(v.Arg) {
+			var err error
+
+			switch typ.Kind {
+			case model.TypeKindInt:
+				_, err = strconv.ParseInt(value, 10, 64)
+
+			case model.TypeKindFloat:
+				_, err = strconv.ParseFloat(value, 64)
+			}
+
+			if err != nil {
+				return fmt.Errorf("value='%s' of the validation rule='%s' is not a number", value, v.Rule)
+			}
+
+			if (typ.Kind == model.TypeKindInt || typ.Kind == model.TypeKindFloat) && !resolver.isRepresentable(value, expr) {
+				return fmt.Errorf("value='%s' of the validation rule='%s' overflows the field type='%s'",
+					value, v.Rule, typ.Name)
+			}
+		}
+
+	case labels.ValidationNonZero:
+		switch typ.Kind {
+		case model.TypeKindStruct, model.TypeKindArray:
+			applicable = resolver.isComparable(expr)
+
+		case model.TypeKindOther:
+			applicable = false
+
+		default:
+			applicable = true
+		}
 	}
 
-	return imports
+	if !applicable {
+		return fmt.Errorf("validation rule='%s' is not applicable to the field type='%s'", v.Rule, typ.Name)
+	}
+
+	return nil
+}
+
+func isTypeKindWithLen(kind model.TypeKind) bool {
+	return kind == model.TypeKindString ||
+		kind == model.TypeKindSlice ||
+		kind == model.TypeKindMap ||
+		kind == model.TypeKindArray
 }
 
-func (s *parser) containCommentLabels(decl *ast.GenDecl) bool {
-	if decl.Doc == nil {
-		return false
+func getFieldNames(f *ast.Field) []string {
+	if len(f.Names) == 0 {
+		return []string{getFieldName(f)}
 	}
 
-	for _, comment := range decl.Doc.List {
-		if containsAll(comment.Text, s.commentLabels) {
-			return true
+	res := make([]string, 0, len(f.Names))
+
+	for _, name := range f.Names {
+		// blank fields can't be set
+		if name.Name != "_" {
+			res = append(res, name.Name)
 		}
 	}
 
-	return false
+	return res
 }
 
-func containsAll(text string, ss []string) bool {
-	for _, s := range ss {
-		if !strings.Contains(text, s) {
-			return false
-		}
+func getFieldName(f *ast.Field) string {
+	if len(f.Names) > 0 {
+		return f.Names[0].Name
 	}
 
-	return true
+	// embedded field
+	expr := f.Type
+
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+
+		case *ast.IndexExpr:
+			expr = e.X
+
+		case *ast.IndexListExpr:
+			expr = e.X
+
+		case *ast.SelectorExpr:
+			return e.Sel.Name
+
+		case *ast.Ident:
+			return e.Name
+
+		default:
+			return gotypes.ExprString(f.Type)
+		}
+	}
 }
 
 func isStringCapital(s string) bool {
@@ -1,11 +1,14 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,6 +20,13 @@ func ptr[T any](t T) *T {
 	return &t
 }
 
+// parseSource parses the source as the input.go file of the dir without writing it to the disk.
+func parseSource(t *testing.T, dir, source string) (*model.File, error) {
+	t.Helper()
+
+	return NewParser().Parse(context.Background(), filepath.Join(dir, "input.go"), strings.NewReader(source))
+}
+
 func TestParser_Parse(t *testing.T) {
 	cases := []struct {
 		name        string
@@ -33,6 +43,8 @@ func TestParser_Parse(t *testing.T) {
 				Pkg:     "test42",
 				Imports: []model.Import{},
 				Structs: []model.Struct{},
+				Others:  nil,
+				Decls:   []string{},
 			},
 			expectedErr: nil,
 		},
@@ -50,6 +62,8 @@ func TestParser_Parse(t *testing.T) {
 				Pkg:     "main",
 				Imports: []model.Import{},
 				Structs: []model.Struct{},
+				Others:  nil,
+				Decls:   []string{"A"},
 			},
 			expectedErr: nil,
 		},
@@ -75,21 +89,37 @@ func TestParser_Parse(t *testing.T) {
 				},
 				Structs: []model.Struct{
 					{
-						Name:    "A",
-						Private: false,
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
 						Fields: []model.Field{
 							{
 								Name: "F1",
 								Type: model.FieldType{
-									Name: "t1.Time",
-									Info: model.TypeInfoOther,
+									Name:   "t1.Time",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindStruct,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: true,
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 						},
 					},
 				},
+				Others: nil,
+				Decls:  []string{"A"},
 			},
 			expectedErr: nil,
 		},
@@ -117,21 +147,37 @@ func TestParser_Parse(t *testing.T) {
 				},
 				Structs: []model.Struct{
 					{
-						Name:    "A",
-						Private: false,
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
 						Fields: []model.Field{
 							{
 								Name: "F1",
 								Type: model.FieldType{
-									Name: "time.Time",
-									Info: model.TypeInfoOther,
+									Name:   "time.Time",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindStruct,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: true,
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 						},
 					},
 				},
+				Others: nil,
+				Decls:  []string{"A"},
 			},
 			expectedErr: nil,
 		},
@@ -152,30 +198,55 @@ func TestParser_Parse(t *testing.T) {
 				Imports: []model.Import{},
 				Structs: []model.Struct{
 					{
-						Name:    "A",
-						Private: false,
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
 						Fields: []model.Field{
 							{
 								Name: "F1",
 								Type: model.FieldType{
-									Name: "int",
-									Info: model.TypeInfoOther,
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: true,
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 							{
 								Name: "f2",
 								Type: model.FieldType{
-									Name: "string",
-									Info: model.TypeInfoOther,
+									Name:   "string",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindString,
+									NoCopy: false,
 								},
-								Private:  true,
-								Required: true,
+								Private:     true,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 						},
 					},
 				},
+				Others: nil,
+				Decls:  []string{"A"},
 			},
 			expectedErr: nil,
 		},
@@ -198,48 +269,91 @@ func TestParser_Parse(t *testing.T) {
 				Imports: []model.Import{},
 				Structs: []model.Struct{
 					{
-						Name:    "A",
-						Private: false,
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
 						Fields: []model.Field{
 							{
 								Name: "F1",
 								Type: model.FieldType{
-									Name: "int",
-									Info: model.TypeInfoOther,
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: true,
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 							{
 								Name: "F2",
 								Type: model.FieldType{
-									Name: "*int",
-									Info: model.TypeInfoPointer,
+									Name:   "*int",
+									Elem:   "int",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: false,
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 							{
 								Name: "F3",
 								Type: model.FieldType{
-									Name: "int",
-									Info: model.TypeInfoOther,
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: false,
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 							{
 								Name: "F4",
 								Type: model.FieldType{
-									Name: "*int",
-									Info: model.TypeInfoPointer,
+									Name:   "*int",
+									Elem:   "int",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: true,
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 						},
 					},
 				},
+				Others: nil,
+				Decls:  []string{"A"},
 			},
 			expectedErr: nil,
 		},
@@ -247,6 +361,8 @@ func TestParser_Parse(t *testing.T) {
 			name: "feature flags",
 			source: `
 			package main
+
+			import "github.com/samber/mo"
 			
 			//go:generate gosb -source=input.go -features=ptr,arr,opt
 			type A struct {
@@ -255,6 +371,123 @@ func TestParser_Parse(t *testing.T) {
 				F3 mo.Option[int]
 				F4 mo.Option[int] ` + "`gosb:\"required\"`" + `
 			}`,
+			expected: &model.File{
+				Name: "x",
+				Path: "x",
+				Pkg:  "main",
+				Imports: []model.Import{
+					{
+						Value: `"github.com/samber/mo"`,
+						Alias: nil,
+					},
+				},
+				Structs: []model.Struct{
+					{
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F1",
+								Type: model.FieldType{
+									Name:   "**int",
+									Elem:   "*int",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "F2",
+								Type: model.FieldType{
+									Name:   "[]int",
+									Elem:   "int",
+									Key:    "",
+									Info:   model.TypeInfoArray,
+									Kind:   model.TypeKindSlice,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "F3",
+								Type: model.FieldType{
+									Name:   "mo.Option[int]",
+									Elem:   "int",
+									Key:    "",
+									Info:   model.TypeInfoOption,
+									Kind:   model.TypeKindOther,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "F4",
+								Type: model.FieldType{
+									Name:   "mo.Option[int]",
+									Elem:   "int",
+									Key:    "",
+									Info:   model.TypeInfoOption,
+									Kind:   model.TypeKindOther,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{"A"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "struct containing a lock",
+			source: `
+			package main
+
+			import "sync"
+
+			type counter struct {
+				mu sync.Mutex
+				n  int
+			}
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 int
+				c  [1]counter ` + "`gosb:\"-\"`" + `
+			}`,
 			expected: &model.File{
 				Name:    "x",
 				Path:    "x",
@@ -262,90 +495,2164 @@ func TestParser_Parse(t *testing.T) {
 				Imports: []model.Import{},
 				Structs: []model.Struct{
 					{
-						Name:    "A",
-						Private: false,
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     true,
 						Fields: []model.Field{
 							{
 								Name: "F1",
 								Type: model.FieldType{
-									Name: "**int",
-									Info: model.TypeInfoPointer,
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: false,
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{"A", "counter"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "named types and aliased imports",
+			source: `
+			package main
+
+			import m "github.com/samber/mo"
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 Ints
+				F2 m.Option[string]
+			}
+
+			type Ints []int`,
+			expected: &model.File{
+				Name: "x",
+				Path: "x",
+				Pkg:  "main",
+				Imports: []model.Import{
+					{
+						Value: `"github.com/samber/mo"`,
+						Alias: ptr("m"),
+					},
+				},
+				Structs: []model.Struct{
+					{
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F1",
+								Type: model.FieldType{
+									Name:   "Ints",
+									Elem:   "int",
+									Key:    "",
+									Info:   model.TypeInfoArray,
+									Kind:   model.TypeKindSlice,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 							{
 								Name: "F2",
 								Type: model.FieldType{
-									Name: "[]int",
-									Info: model.TypeInfoArray,
+									Name:   "m.Option[string]",
+									Elem:   "string",
+									Key:    "",
+									Info:   model.TypeInfoOption,
+									Kind:   model.TypeKindOther,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: true,
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{"A", "Ints"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "generic struct",
+			source: `
+			package main
+
+			import "fmt"
+
+			//go:generate gosb -source=input.go
+			type A[K comparable, V fmt.Stringer] struct {
+				F1 map[K]V
+				F2 []V
+			}`,
+			expected: &model.File{
+				Name: "x",
+				Path: "x",
+				Pkg:  "main",
+				Imports: []model.Import{
+					{
+						Value: `"fmt"`,
+						Alias: nil,
+					},
+				},
+				Structs: []model.Struct{
+					{
+						Name:      "A",
+						Private:   false,
+						Qualifier: "",
+						TypeParams: []model.TypeParam{
+							{
+								Name:       "K",
+								Constraint: "comparable",
+							},
+							{
+								Name:       "V",
+								Constraint: "fmt.Stringer",
+							},
+						},
+						Directive: nil,
+						Methods:   nil,
+						NoCopy:    false,
+						Fields: []model.Field{
+							{
+								Name: "F1",
+								Type: model.FieldType{
+									Name:   "map[K]V",
+									Elem:   "V",
+									Key:    "K",
+									Info:   model.TypeInfoMap,
+									Kind:   model.TypeKindMap,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "F2",
+								Type: model.FieldType{
+									Name:   "[]V",
+									Elem:   "V",
+									Key:    "",
+									Info:   model.TypeInfoArray,
+									Kind:   model.TypeKindSlice,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{"A"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "default values",
+			source: `
+			package main
+
+			import "time"
+
+			const defaultName = "name"
+
+			func newID() int64 { return 1 }
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 int ` + "`gosb:\"default=10\"`" + `
+				F2 string ` + "`gosb:\"default=defaultName\"`" + `
+				F3 int64 ` + "`gosb:\"optional,default=newID()\"`" + `
+				F4 time.Duration ` + "`gosb:\"default=time.Second\"`" + `
+			}`,
+			expected: &model.File{
+				Name: "x",
+				Path: "x",
+				Pkg:  "main",
+				Imports: []model.Import{
+					{
+						Value: `"time"`,
+						Alias: nil,
+					},
+				},
+				Structs: []model.Struct{
+					{
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F1",
+								Type: model.FieldType{
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "10",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "F2",
+								Type: model.FieldType{
+									Name:   "string",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindString,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "defaultName",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 							{
 								Name: "F3",
 								Type: model.FieldType{
-									Name: "mo.Option[int]",
-									Info: model.TypeInfoOption,
+									Name:   "int64",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: false,
+								Private:     false,
+								Required:    false,
+								Default:     "newID()",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 							{
 								Name: "F4",
 								Type: model.FieldType{
-									Name: "mo.Option[int]",
-									Info: model.TypeInfoOption,
+									Name:   "time.Duration",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
 								},
-								Private:  false,
-								Required: true,
+								Private:     false,
+								Required:    false,
+								Default:     "time.Second",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
 							},
 						},
 					},
 				},
+				Others: nil,
+				Decls:  []string{"A", "defaultName", "newID"},
 			},
 			expectedErr: nil,
 		},
 		{
-			name: "struct not found error",
+			name: "default value of a wrong type",
 			source: `
 			package main
-			
+
 			//go:generate gosb -source=input.go
-			type A interface {
-				M1()
+			type A struct {
+				F1 int ` + "`gosb:\"default=\\\"abc\\\"\"`" + `
 			}`,
-			expected:    nil,
-			expectedErr: fmt.Errorf("parsing struct: %w", errors.New("struct not found")),
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				fmt.Errorf("parsing A.F1 field: %w",
+					fmt.Errorf("%s: %w", "input.go:6:12",
+						errors.New(`default value='"abc"' of type untyped string is not assignable to int`)))),
 		},
-	}
+		{
+			name: "default value calling a function with arguments",
+			source: `
+			package main
 
-	for _, c := range cases {
-		c := c
-		t.Run(c.name, func(t *testing.T) {
-			f, err := os.CreateTemp("", "")
-			require.NoError(t, err, "creating a temp file")
+			func id(v int) int { return v }
 
-			defer func() {
-				assert.NoError(t, f.Close(), "closing the temp file")
-				assert.NoError(t, os.Remove(f.Name()), "deleting the temp file")
-			}()
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 int ` + "`gosb:\"default=id(1)\"`" + `
+			}`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				fmt.Errorf("parsing A.F1 field: %w",
+					fmt.Errorf("%s: %w", "input.go:8:12",
+						errors.New("default value='id(1)' must be a function call without arguments")))),
+		},
+		{
+			name: "validations",
+			source: `
+			package main
 
-			_, _ = f.WriteString(c.source)
-			_, _ = f.Seek(0, 0)
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 int ` + "`gosb:\"min=1,max=10\"`" + `
+				F2 []string ` + "`gosb:\"len=2\"`" + `
+			}`,
+			expected: &model.File{
+				Name:    "x",
+				Path:    "x",
+				Pkg:     "main",
+				Imports: []model.Import{},
+				Structs: []model.Struct{
+					{
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F1",
+								Type: model.FieldType{
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
+								},
+								Private:  false,
+								Required: true,
+								Default:  "",
+								Validations: []model.Validation{
+									{Rule: "min", Arg: "1"},
+									{Rule: "max", Arg: "10"},
+								},
+								Nested:   nil,
+								Alias:    "",
+								Readonly: false,
+							},
+							{
+								Name: "F2",
+								Type: model.FieldType{
+									Name:   "[]string",
+									Elem:   "string",
+									Key:    "",
+									Info:   model.TypeInfoArray,
+									Kind:   model.TypeKindSlice,
+									NoCopy: false,
+								},
+								Private:  false,
+								Required: true,
+								Default:  "",
+								Validations: []model.Validation{
+									{Rule: "len", Arg: "2"},
+								},
+								Nested:   nil,
+								Alias:    "",
+								Readonly: false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{"A"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "validation not applicable to the field type",
+			source: `
+			package main
 
-			s := NewParser()
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 int ` + "`gosb:\"regex=^a$\"`" + `
+			}`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				fmt.Errorf("parsing A.F1 field: %w",
+					fmt.Errorf("%s: %w", "input.go:6:12",
+						errors.New("validation rule='regex' is not applicable to the field type='int'")))),
+		},
+		{
+			name: "float validation value of an integer field",
+			source: `
+			package main
 
-			// because of the dynamic file generation, rewrite the name and the path
-			if c.expected != nil {
-				c.expected.Name = filepath.Base(f.Name())
-				c.expected.Path = filepath.Dir(f.Name())
-			}
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 []int ` + "`gosb:\"min=1.5\"`" + `
+			}`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				fmt.Errorf("parsing A.F1 field: %w",
+					fmt.Errorf("%s: %w", "input.go:6:14",
+						errors.New("value='1.5' of the validation rule='min' is not an integer")))),
+		},
+		{
+			name: "validation value overflowing the field type",
+			source: `
+			package main
 
-			actual, actualErr := s.Parse(f)
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 uint8 ` + "`gosb:\"min=0,max=300\"`" + `
+			}`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				fmt.Errorf("parsing A.F1 field: %w",
+					fmt.Errorf("%s: %w", "input.go:6:14",
+						errors.New("value='300' of the validation rule='max' overflows the field type='uint8'")))),
+		},
+		{
+			name: "oneof validation value overflowing the field type",
+			source: `
+			package main
+
+			type Level int8
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 Level ` + "`gosb:\"oneof=-128 -129\"`" + `
+			}`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				fmt.Errorf("parsing A.F1 field: %w",
+					fmt.Errorf("%s: %w", "input.go:8:14",
+						errors.New("value='-129' of the validation rule='oneof' overflows the field type='Level'")))),
+		},
+		{
+			name: "misspelled tag option",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 *int ` + "`gosb:\"requried\"`" + `
+			}`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				fmt.Errorf("parsing A.F1 field: %w",
+					fmt.Errorf("%s: %w", "input.go:6:13",
+						fmt.Errorf("parsing field tag: %w", errors.New("unknown tag option='requried'"))))),
+		},
+		{
+			name: "malformed struct tag",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 *int ` + "`json:\"f1\" gosb:required`" + `
+			}`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				fmt.Errorf("parsing A.F1 field: %w",
+					fmt.Errorf("%s: %w", "input.go:6:13",
+						errors.New(`malformed struct tag='json:"f1" gosb:required'`)))),
+		},
+		{
+			name: "setter tags",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 string ` + "`gosb:\"name=Host\"`" + `
+				f2 *int   ` + "`gosb:\"readonly\"`" + `
+				F3 int    ` + "`gosb:\"-\"`" + `
+			}`,
+			expected: &model.File{
+				Name:    "x",
+				Path:    "x",
+				Pkg:     "main",
+				Imports: []model.Import{},
+				Structs: []model.Struct{
+					{
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F1",
+								Type: model.FieldType{
+									Name:   "string",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindString,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "Host",
+								Readonly:    false,
+							},
+							{
+								Name: "f2",
+								Type: model.FieldType{
+									Name:   "*int",
+									Elem:   "int",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
+								},
+								Private:     true,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    true,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{"A"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "nested builders",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1 Address
+				F2 *c
+			}
+
+			//go:generate gosb -source=input.go
+			type Address struct {
+				City string
+			}
+
+			//go:generate gosb -source=input.go
+			type c struct {
+				F3 *int
+			}`,
+			expected: &model.File{
+				Name:    "x",
+				Path:    "x",
+				Pkg:     "main",
+				Imports: []model.Import{},
+				Structs: []model.Struct{
+					{
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F1",
+								Type: model.FieldType{
+									Name:   "Address",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindStruct,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested: &model.Nested{
+									Struct:    "Address",
+									Private:   false,
+									Required:  true,
+									Validated: false,
+									Nested:    nil,
+									Directive: nil,
+								},
+								Alias:    "",
+								Readonly: false,
+							},
+							{
+								Name: "F2",
+								Type: model.FieldType{
+									Name:   "*c",
+									Elem:   "c",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested: &model.Nested{
+									Struct:    "c",
+									Private:   true,
+									Required:  false,
+									Validated: false,
+									Nested:    nil,
+									Directive: nil,
+								},
+								Alias:    "",
+								Readonly: false,
+							},
+						},
+					},
+					{
+						Name:       "Address",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "City",
+								Type: model.FieldType{
+									Name:   "string",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindString,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+					{
+						Name:       "c",
+						Private:    true,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F3",
+								Type: model.FieldType{
+									Name:   "*int",
+									Elem:   "int",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{"A", "Address", "c"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "grouped declarations",
+			source: `
+			package main
+
+			type (
+				//go:generate gosb -source=input.go
+				A struct {
+					F1 C
+				}
+
+				B struct {
+					F2 int
+				}
+
+				// C is annotated by its own comment
+				//go:generate gosb -source=input.go
+				C struct {
+					F3 int
+				}
+			)
+
+			//go:generate gosb -source=input.go
+			type (
+				D struct {
+					F4 int
+				}
+
+				I interface{}
+
+				E struct {
+					F5 int
+				}
+			)`,
+			expected: &model.File{
+				Name:    "x",
+				Path:    "x",
+				Pkg:     "main",
+				Imports: []model.Import{},
+				Structs: []model.Struct{
+					{
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F1",
+								Type: model.FieldType{
+									Name:   "C",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindStruct,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested: &model.Nested{
+									Struct:    "C",
+									Private:   false,
+									Required:  true,
+									Validated: false,
+									Nested:    nil,
+									Directive: nil,
+								},
+								Alias:    "",
+								Readonly: false,
+							},
+						},
+					},
+					{
+						Name:       "C",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F3",
+								Type: model.FieldType{
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+					{
+						Name:       "D",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F4",
+								Type: model.FieldType{
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+					{
+						Name:       "E",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F5",
+								Type: model.FieldType{
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{"A", "B", "C", "D", "E", "I"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "annotated type of a grouped declaration is not a struct",
+			source: `
+			package main
+
+			type (
+				A struct {
+					F1 int
+				}
+
+				//go:generate gosb -source=input.go
+				I interface{}
+			)`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				errors.New("input.go:10:5: type='I' annotated by go:generate is not a struct")),
+		},
+		{
+			name: "gosb builder directives",
+			source: `
+			package main
+
+			// A is generated by the step builder
+			//gosb:builder mode=step setter="With{{ .Field }}"
+			type A struct {
+				F1 int
+			}
+
+			//gosb:builder
+			type B struct {
+				F2 C
+			}
+
+			//gosb:builder features=ptr mode=builder
+			type (
+				//gosb:builder features=arr,map
+				C struct {
+					F3 int
+				}
+			)
+
+			// D mentions gosb and go:generate, but it's not annotated
+			type D struct {
+				F4 int
+			}`,
+			expected: &model.File{
+				Name:    "x",
+				Path:    "x",
+				Pkg:     "main",
+				Imports: []model.Import{},
+				Structs: []model.Struct{
+					{
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  map[string]string{"mode": "step", "setter": "With{{ .Field }}"},
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F1",
+								Type: model.FieldType{
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+					{
+						Name:       "B",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F2",
+								Type: model.FieldType{
+									Name:   "C",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindStruct,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested: &model.Nested{
+									Struct:    "C",
+									Private:   false,
+									Required:  true,
+									Validated: false,
+									Nested:    nil,
+									Directive: map[string]string{"features": "arr,map", "mode": "builder"},
+								},
+								Alias:    "",
+								Readonly: false,
+							},
+						},
+					},
+					{
+						Name:       "C",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  map[string]string{"features": "arr,map", "mode": "builder"},
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F3",
+								Type: model.FieldType{
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    true,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{"A", "B", "C", "D"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "unknown gosb builder directive option",
+			source: `
+			package main
+
+			//gosb:builder mode=step kind=builder
+			type A struct {
+				F1 int
+			}`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				fmt.Errorf("%s: %w", "input.go:4:4", errors.New("unknown directive option='kind'"))),
+		},
+		{
+			name: "multi-name fields",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1, F2 int ` + "`gosb:\"optional,min=1\"`" + `
+				f3, F4 *string
+				_, _   int
+			}`,
+			expected: &model.File{
+				Name:    "x",
+				Path:    "x",
+				Pkg:     "main",
+				Imports: []model.Import{},
+				Structs: []model.Struct{
+					{
+						Name:       "A",
+						Private:    false,
+						Qualifier:  "",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "F1",
+								Type: model.FieldType{
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: []model.Validation{{Rule: "min", Arg: "1"}},
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "F2",
+								Type: model.FieldType{
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: []model.Validation{{Rule: "min", Arg: "1"}},
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "f3",
+								Type: model.FieldType{
+									Name:   "*string",
+									Elem:   "string",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
+								},
+								Private:     true,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "F4",
+								Type: model.FieldType{
+									Name:   "*string",
+									Elem:   "string",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{"A"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "renamed multi-name fields",
+			source: `
+			package main
+
+			//go:generate gosb -source=input.go
+			type A struct {
+				F1, F2 int ` + "`gosb:\"name=F\"`" + `
+			}`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				fmt.Errorf("parsing A.F1 field: %w",
+					errors.New("input.go:6:16: tag option='name' can't be used by the declaration of several fields"))),
+		},
+		{
+			name: "struct not found error",
+			source: `
+			package main
+			
+			//go:generate gosb -source=input.go
+			type A interface {
+				M1()
+			}`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				errors.New("input.go:5:9: type='A' annotated by go:generate is not a struct")),
+		},
+		{
+			name: "gosb:builder directive of a non-struct type",
+			source: `
+			package main
+
+			//gosb:builder mode=step
+			type A int`,
+			expected: nil,
+			expectedErr: fmt.Errorf("parsing struct: %w",
+				errors.New("input.go:5:9: type='A' annotated by gosb:builder is not a struct")),
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			// because of the dynamic directory, rewrite the name and the path
+			if c.expected != nil {
+				c.expected.Name = "input.go"
+				c.expected.Path = dir
+			}
+
+			actual, actualErr := parseSource(t, dir, c.source)
+			requireErrorEqual(t, dir, c.expectedErr, actualErr)
+			assert.Equal(t, c.expected, actual, "values are not equal")
+		})
+	}
+}
+
+// requireErrorEqual compares the errors having the source positions relative to the directory.
+func requireErrorEqual(t *testing.T, dir string, expected, actual error) {
+	t.Helper()
+
+	if expected == nil {
+		require.NoError(t, actual)
+
+		return
+	}
+
+	require.Error(t, actual)
+	require.Equal(t, expected.Error(), strings.ReplaceAll(actual.Error(), dir+string(filepath.Separator), ""),
+		"errors are not equal")
+}
+
+func TestParser_Parse_PackageTypes(t *testing.T) {
+	dir := t.TempDir()
+
+	types := `
+	package main
+
+	import "time"
+
+	type Stamps []time.Time
+
+	type Ref = *time.Time`
+
+	// the whole package is loaded only within a module
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module test\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "types.go"), []byte(types), 0o600))
+
+	source := `
+	package main
+
+	//go:generate gosb -source=input.go
+	type A struct {
+		F1 Stamps
+		F2 Ref
+	}`
+
+	expected := &model.File{
+		Name: "input.go",
+		Path: dir,
+		Pkg:  "main",
+		Imports: []model.Import{
+			{
+				Value: `"time"`,
+				Alias: nil,
+			},
+		},
+		Structs: []model.Struct{
+			{
+				Name:       "A",
+				Private:    false,
+				Qualifier:  "",
+				TypeParams: nil,
+				Directive:  nil,
+				Methods:    nil,
+				NoCopy:     false,
+				Fields: []model.Field{
+					{
+						Name: "F1",
+						Type: model.FieldType{
+							Name:   "Stamps",
+							Elem:   "time.Time",
+							Key:    "",
+							Info:   model.TypeInfoArray,
+							Kind:   model.TypeKindSlice,
+							NoCopy: false,
+						},
+						Private:     false,
+						Required:    true,
+						Default:     "",
+						Validations: nil,
+						Nested:      nil,
+						Alias:       "",
+						Readonly:    false,
+					},
+					{
+						Name: "F2",
+						Type: model.FieldType{
+							Name:   "Ref",
+							Elem:   "time.Time",
+							Key:    "",
+							Info:   model.TypeInfoPointer,
+							Kind:   model.TypeKindNillable,
+							NoCopy: false,
+						},
+						Private:     false,
+						Required:    false,
+						Default:     "",
+						Validations: nil,
+						Nested:      nil,
+						Alias:       "",
+						Readonly:    false,
+					},
+				},
+			},
+		},
+		Others: nil,
+		Decls:  []string{"A", "Ref", "Stamps"},
+	}
+
+	actual, err := parseSource(t, dir, source)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestParser_ParsePackages(t *testing.T) {
+	dir := t.TempDir()
+
+	sources := map[string]string{
+		"go.mod": "module test\n",
+		"a/x.go": `
+		package a
+
+		//go:generate gosb -source=x.go
+		type X struct {
+			F1 int
+		}`,
+		"a/y.go": `
+		package a
+
+		type Y struct {
+			F1 int
+		}`,
+		"b/z.go": `
+		package b
+
+		import "time"
+
+		//go:generate gosb -source=z.go
+		type Z struct {
+			F1 *time.Time
+		}`,
+	}
+
+	writeSources(t, dir, sources)
+
+	expected := []*model.File{
+		{
+			Name:    "x.go",
+			Path:    filepath.Join(dir, "a"),
+			Pkg:     "a",
+			Imports: []model.Import{},
+			Structs: []model.Struct{
+				{
+					Name:       "X",
+					Private:    false,
+					Qualifier:  "",
+					TypeParams: nil,
+					Directive:  nil,
+					Methods:    nil,
+					NoCopy:     false,
+					Fields: []model.Field{
+						{
+							Name: "F1",
+							Type: model.FieldType{
+								Name:   "int",
+								Elem:   "",
+								Key:    "",
+								Info:   model.TypeInfoOther,
+								Kind:   model.TypeKindInt,
+								NoCopy: false,
+							},
+							Private:     false,
+							Required:    true,
+							Default:     "",
+							Validations: nil,
+							Nested:      nil,
+							Alias:       "",
+							Readonly:    false,
+						},
+					},
+				},
+			},
+			Others: nil,
+			Decls:  []string{"X", "Y"},
+		},
+		{
+			Name: "z.go",
+			Path: filepath.Join(dir, "b"),
+			Pkg:  "b",
+			Imports: []model.Import{
+				{
+					Value: `"time"`,
+					Alias: nil,
+				},
+			},
+			Structs: []model.Struct{
+				{
+					Name:       "Z",
+					Private:    false,
+					Qualifier:  "",
+					TypeParams: nil,
+					Directive:  nil,
+					Methods:    nil,
+					NoCopy:     false,
+					Fields: []model.Field{
+						{
+							Name: "F1",
+							Type: model.FieldType{
+								Name:   "*time.Time",
+								Elem:   "time.Time",
+								Key:    "",
+								Info:   model.TypeInfoPointer,
+								Kind:   model.TypeKindNillable,
+								NoCopy: false,
+							},
+							Private:     false,
+							Required:    false,
+							Default:     "",
+							Validations: nil,
+							Nested:      nil,
+							Alias:       "",
+							Readonly:    false,
+						},
+					},
+				},
+			},
+			Others: nil,
+			Decls:  []string{"Z"},
+		},
+	}
+
+	actual, err := NewParser().ParsePackages(context.Background(), dir, "./...")
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+// writeSources writes the sources keyed by the file names relative to the dir.
+func writeSources(t *testing.T, dir string, sources map[string]string) {
+	t.Helper()
+
+	for name, source := range sources {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0o700))
+		require.NoError(t, os.WriteFile(filename, []byte(source), 0o600))
+	}
+}
+
+// typeSources are the sources of a module declaring struct types in the ext package,
+// the builders of which are generated into the out package.
+var typeSources = map[string]string{
+	"go.mod": "module test\n",
+	"ext/ext.go": `package ext
+
+import (
+	"math/rand"
+	randv2 "math/rand/v2"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	Host    string
+	Timeout *time.Duration
+	Tags    map[string]Tag
+	Tag
+	secret string
+	Inner  inner
+	Hooks  map[string]func(*inner)
+}
+
+type Tag struct {
+	Name string
+}
+
+type inner struct {
+	Value int
+}
+
+type Limit int
+
+type Pool struct {
+	mu    sync.Mutex
+	Size  int
+	Mutex sync.Mutex
+	Next  *Pool
+}
+
+type Random struct {
+	V1 *rand.Rand
+	V2 *randv2.Rand
+}
+
+type config struct {
+	Host string
+}`,
+	"out/out.go": "package out\n",
+}
+
+func TestParser_ParseType(t *testing.T) {
+	dir := t.TempDir()
+	writeSources(t, dir, typeSources)
+
+	cases := []struct {
+		name        string
+		typeName    string
+		expected    *model.File
+		expectedErr error
+	}{
+		{
+			name:     "exported fields",
+			typeName: "test/ext.Config",
+			expected: &model.File{
+				Name: "test/ext.Config",
+				Path: filepath.Join(dir, "out"),
+				Pkg:  "out",
+				Imports: []model.Import{
+					{
+						Value: `"time"`,
+						Alias: nil,
+					},
+					{
+						Value: `"test/ext"`,
+						Alias: nil,
+					},
+				},
+				Structs: []model.Struct{
+					{
+						Name:       "Config",
+						Private:    false,
+						Qualifier:  "ext",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "Host",
+								Type: model.FieldType{
+									Name:   "string",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindString,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "Timeout",
+								Type: model.FieldType{
+									Name:   "*time.Duration",
+									Elem:   "time.Duration",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "Tags",
+								Type: model.FieldType{
+									Name:   "map[string]ext.Tag",
+									Elem:   "ext.Tag",
+									Key:    "string",
+									Info:   model.TypeInfoMap,
+									Kind:   model.TypeKindMap,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "Tag",
+								Type: model.FieldType{
+									Name:   "ext.Tag",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindStruct,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "type containing a lock",
+			typeName: "test/ext.Pool",
+			expected: &model.File{
+				Name: "test/ext.Pool",
+				Path: filepath.Join(dir, "out"),
+				Pkg:  "out",
+				Imports: []model.Import{
+					{
+						Value: `"test/ext"`,
+						Alias: nil,
+					},
+				},
+				Structs: []model.Struct{
+					{
+						Name:       "Pool",
+						Private:    false,
+						Qualifier:  "ext",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     true,
+						Fields: []model.Field{
+							{
+								Name: "Size",
+								Type: model.FieldType{
+									Name:   "int",
+									Elem:   "",
+									Key:    "",
+									Info:   model.TypeInfoOther,
+									Kind:   model.TypeKindInt,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "Next",
+								Type: model.FieldType{
+									Name:   "*ext.Pool",
+									Elem:   "ext.Pool",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: true,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "packages of the same name",
+			typeName: "test/ext.Random",
+			expected: &model.File{
+				Name: "test/ext.Random",
+				Path: filepath.Join(dir, "out"),
+				Pkg:  "out",
+				Imports: []model.Import{
+					{
+						Value: `"math/rand"`,
+						Alias: nil,
+					},
+					{
+						Value: `"math/rand/v2"`,
+						Alias: ptr("rand2"),
+					},
+					{
+						Value: `"test/ext"`,
+						Alias: nil,
+					},
+				},
+				Structs: []model.Struct{
+					{
+						Name:       "Random",
+						Private:    false,
+						Qualifier:  "ext",
+						TypeParams: nil,
+						Directive:  nil,
+						Methods:    nil,
+						NoCopy:     false,
+						Fields: []model.Field{
+							{
+								Name: "V1",
+								Type: model.FieldType{
+									Name:   "*rand.Rand",
+									Elem:   "rand.Rand",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+							{
+								Name: "V2",
+								Type: model.FieldType{
+									Name:   "*rand2.Rand",
+									Elem:   "rand2.Rand",
+									Key:    "",
+									Info:   model.TypeInfoPointer,
+									Kind:   model.TypeKindNillable,
+									NoCopy: false,
+								},
+								Private:     false,
+								Required:    false,
+								Default:     "",
+								Validations: nil,
+								Nested:      nil,
+								Alias:       "",
+								Readonly:    false,
+							},
+						},
+					},
+				},
+				Others: nil,
+				Decls:  []string{},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "unqualified type",
+			typeName:    "Config",
+			expected:    nil,
+			expectedErr: errors.New("type='Config' must be qualified by the import path, e.g. net/http.Server"),
+		},
+		{
+			name:        "type not found",
+			typeName:    "test/ext.Server",
+			expected:    nil,
+			expectedErr: errors.New("type='Server' not found in the package='test/ext'"),
+		},
+		{
+			name:        "unexported type",
+			typeName:    "test/ext.config",
+			expected:    nil,
+			expectedErr: errors.New("type='config' of the package='test/ext' is not exported"),
+		},
+		{
+			name:        "not a struct",
+			typeName:    "test/ext.Limit",
+			expected:    nil,
+			expectedErr: errors.New("type='Limit' of the package='test/ext' is not a struct"),
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			actual, actualErr := NewParser().ParseType(context.Background(), filepath.Join(dir, "out"), c.typeName)
 			require.Equal(t, c.expectedErr, actualErr, "errors are not equal")
-			assert.Equal(t, c.expected, actual, "values are not equal")
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func TestParser_ParsePackages_OutputPackage(t *testing.T) {
+	dir := t.TempDir()
+
+	writeSources(t, dir, map[string]string{
+		"go.mod": "module test\n",
+		"model/model.go": `package model
+
+import "time"
+
+const DefaultPort = 8080
+
+//gosb:builder
+type Server struct {
+	Port    int           ` + "`gosb:\"default=DefaultPort\"`" + `
+	Timeout time.Duration ` + "`gosb:\"default=time.Second\"`" + `
+	Tags    []Tag
+	secret  string ` + "`gosb:\"-\"`" + `
+}
+
+type Tag string`,
+		"builders/builders.go": "package builders\n\ntype ServerFactory struct{}\n\nfunc init() {}",
+		"builders/model_builder.go": "// Code generated by our tool. DO NOT EDIT.\n\n" +
+			"package builders\n\ntype ServerBuilder struct{}",
+		"builders/builders_test.go": "package builders\n\nvar testServer = 1",
+		"bad/bad.go": `package bad
+
+//gosb:builder
+type limits struct {
+	Max int
+}
+
+//gosb:builder
+type Account struct {
+	ID     int
+	role   int
+	Limits *limits
+}`,
+	})
+
+	cases := []struct {
+		name        string
+		pattern     string
+		expected    []*model.File
+		expectedErr error
+	}{
+		{
+			name:    "qualified types",
+			pattern: "./model",
+			expected: []*model.File{
+				{
+					Name: "model.go",
+					Path: filepath.Join(dir, "model"),
+					Pkg:  "builders",
+					Imports: []model.Import{
+						{
+							Value: `"time"`,
+							Alias: nil,
+						},
+						{
+							Value: `"test/model"`,
+							Alias: nil,
+						},
+					},
+					Structs: []model.Struct{
+						{
+							Name:       "Server",
+							Private:    false,
+							Qualifier:  "model",
+							TypeParams: nil,
+							Directive:  nil,
+							Methods:    nil,
+							NoCopy:     false,
+							Fields: []model.Field{
+								{
+									Name: "Port",
+									Type: model.FieldType{
+										Name:   "int",
+										Elem:   "",
+										Key:    "",
+										Info:   model.TypeInfoOther,
+										Kind:   model.TypeKindInt,
+										NoCopy: false,
+									},
+									Private:     false,
+									Required:    false,
+									Default:     "model.DefaultPort",
+									Validations: nil,
+									Nested:      nil,
+									Alias:       "",
+									Readonly:    false,
+								},
+								{
+									Name: "Timeout",
+									Type: model.FieldType{
+										Name:   "time.Duration",
+										Elem:   "",
+										Key:    "",
+										Info:   model.TypeInfoOther,
+										Kind:   model.TypeKindInt,
+										NoCopy: false,
+									},
+									Private:     false,
+									Required:    false,
+									Default:     "time.Second",
+									Validations: nil,
+									Nested:      nil,
+									Alias:       "",
+									Readonly:    false,
+								},
+								{
+									Name: "Tags",
+									Type: model.FieldType{
+										Name:   "[]model.Tag",
+										Elem:   "model.Tag",
+										Key:    "",
+										Info:   model.TypeInfoArray,
+										Kind:   model.TypeKindSlice,
+										NoCopy: false,
+									},
+									Private:     false,
+									Required:    true,
+									Default:     "",
+									Validations: nil,
+									Nested:      nil,
+									Alias:       "",
+									Readonly:    false,
+								},
+							},
+						},
+					},
+					Others: nil,
+					Decls:  []string{"ServerFactory"},
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "structs can't be built outside the package",
+			pattern:  "./bad",
+			expected: nil,
+			expectedErr: errors.New("parsing source file='bad/bad.go': structs can't be built outside " +
+				"the package='test/bad': limits (unexported struct); " +
+				"Account (unexported field='role', field='Limits' of unexported type='limits')"),
+		},
+	}
+
+	output := OutputPackage{
+		Dir:     filepath.Join(dir, "builders"),
+		Name:    "",
+		Resolve: nil,
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			actual, actualErr := NewOutputParser(output).ParsePackages(context.Background(), dir, c.pattern)
+			requireErrorEqual(t, dir, c.expectedErr, actualErr)
+			assert.Equal(t, c.expected, actual)
 		})
 	}
 }
+
+func TestParser_ParsePackages_ResolvedOutputPackage(t *testing.T) {
+	dir := t.TempDir()
+
+	writeSources(t, dir, map[string]string{
+		"go.mod": "module test\n",
+		"model/model.go": `package model
+
+//gosb:builder
+type Server struct {
+	Port int
+}`,
+		"builders/builders.go": "package factory\n\ntype ServerFactory struct{}",
+	})
+
+	output := OutputPackage{
+		Dir:  "",
+		Name: "",
+		Resolve: func(srcDir string) (OutputPackage, error) {
+			require.Equal(t, filepath.Join(dir, "model"), srcDir)
+
+			return OutputPackage{Dir: filepath.Join(dir, "builders"), Name: "factory", Resolve: nil}, nil
+		},
+	}
+
+	actual, err := NewOutputParser(output).ParsePackages(context.Background(), dir, "./model")
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+
+	assert.Equal(t, "factory", actual[0].Pkg)
+	assert.Equal(t, "model", actual[0].Structs[0].Qualifier)
+	assert.Equal(t, []string{"ServerFactory"}, actual[0].Decls)
+}
+
+func TestParser_ParsePackages_RenamedSourcesPackage(t *testing.T) {
+	dir := t.TempDir()
+
+	writeSources(t, dir, map[string]string{
+		"go.mod": "module test\n",
+		"model/model.go": `package model
+
+//gosb:builder
+type Server struct {
+	Port int
+}`,
+	})
+
+	output := OutputPackage{
+		Dir:     filepath.Join(dir, "model"),
+		Name:    "testutil",
+		Resolve: nil,
+	}
+
+	_, actualErr := NewOutputParser(output).ParsePackages(context.Background(), dir, "./model")
+	requireErrorEqual(t, dir, errors.New("parsing source file='model/model.go': output package name='testutil' "+
+		"differs from the package='model' of the sources directory"), actualErr)
+}
+
+func TestParser_ParseSources(t *testing.T) {
+	dir := t.TempDir()
+
+	sources := map[string][]byte{
+		"a.go": []byte(`
+		package main
+
+		//go:generate gosb
+		type A struct {
+			F1 Names
+		}`),
+		"types.go": []byte(`
+		package main
+
+		type Names []string
+
+		func (a *A) Validate() error { return nil }`),
+		// the methods of the files generated by gosb are regenerated along with the builders
+		"a_builder.go": []byte(`// Code generated by go-struct-builder. DO NOT EDIT.
+
+		package main
+
+		func (t *A) ToBuilder() {}`),
+	}
+
+	expected := []*model.File{
+		{
+			Name:    "a.go",
+			Path:    dir,
+			Pkg:     "main",
+			Imports: []model.Import{},
+			Structs: []model.Struct{
+				{
+					Name:       "A",
+					Private:    false,
+					Qualifier:  "",
+					TypeParams: nil,
+					Directive:  nil,
+					Methods:    []string{"Validate"},
+					NoCopy:     false,
+					Fields: []model.Field{
+						{
+							Name: "F1",
+							Type: model.FieldType{
+								Name:   "Names",
+								Elem:   "string",
+								Key:    "",
+								Info:   model.TypeInfoArray,
+								Kind:   model.TypeKindSlice,
+								NoCopy: false,
+							},
+							Private:     false,
+							Required:    true,
+							Default:     "",
+							Validations: nil,
+							Nested:      nil,
+							Alias:       "",
+							Readonly:    false,
+						},
+					},
+				},
+			},
+			Others: nil,
+			Decls:  []string{"A", "Names"},
+		},
+	}
+
+	actual, err := NewParser().ParseSources(context.Background(), dir, sources)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	// the sources are never written to the disk
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = NewParser().ParseSources(context.Background(), dir, map[string][]byte{"../a.go": nil})
+	require.Equal(t, fmt.Errorf("source file='%v' must be in the directory='%v'", "../a.go", dir), err)
+}
+
+func TestParser_Parse_NotExistingFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "not", "created")
+
+	actual, err := parseSource(t, dir, `
+	package main
+
+	import "time"
+
+	//go:generate gosb
+	type A struct {
+		F1 time.Duration
+	}`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "input.go", actual.Name)
+	assert.Equal(t, dir, actual.Path)
+	assert.Equal(t, "time.Duration", actual.Structs[0].Fields[0].Type.Name)
+	assert.Equal(t, model.TypeKindInt, actual.Structs[0].Fields[0].Type.Kind)
+}
+
+func TestParser_ParseFS(t *testing.T) {
+	dir := t.TempDir()
+
+	fsys := fstest.MapFS{
+		"a.go": {Data: []byte(`
+		package main
+
+		//go:generate gosb
+		type A struct {
+			F1 Names
+		}`)},
+		"types.go":      {Data: []byte("package main\n\ntype Names []string")},
+		"types_test.go": {Data: []byte("package main\n\nfunc f() {")},
+		"sub/b.go":      {Data: []byte("package sub\n\nfunc f() {")},
+	}
+
+	actual, err := NewParser().ParseFS(context.Background(), dir, fsys)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+
+	assert.Equal(t, "a.go", actual[0].Name)
+	assert.Equal(t, dir, actual[0].Path)
+	assert.Equal(t, model.TypeKindSlice, actual[0].Structs[0].Fields[0].Type.Kind)
+}
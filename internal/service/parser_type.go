@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	gotoken "go/token"
+	gotypes "go/types"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+// ParseType parses the struct type declared by another package, e.g. net/http.Server,
+// as a struct of the package of the dir, which the builder is generated into.
+// Only the exported fields of exported types are parsed, all of them are optional.
+func (s *parser) ParseType(ctx context.Context, dir, typeName string) (*model.File, error) {
+	importPath, name, err := splitTypeName(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute path of the directory='%v': %w", dir, err)
+	}
+
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packagesLoadMode,
+		Dir:     getExistingDir(dir),
+		Fset:    gotoken.NewFileSet(),
+	}
+
+	patterns := []string{importPath}
+	if cfg.Dir == dir {
+		patterns = append(patterns, ".")
+	}
+
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("loading package='%s': %w", importPath, err)
+	}
+
+	outPkg, typePkg := findTypePackages(pkgs, importPath)
+	if typePkg == nil {
+		return nil, fmt.Errorf("package='%s' not found", importPath)
+	}
+
+	if err = checkTypePackageErrors(typePkg); err != nil {
+		return nil, fmt.Errorf("loading package='%s': %w", importPath, err)
+	}
+
+	outName, outTypes := getOutputPackage(outPkg, dir)
+	if s.output.Name != "" && s.output.Name != outName {
+		if outPkg != nil && outPkg.Name != "" && s.output.Name != outPkg.Name+"_test" {
+			return nil, fmt.Errorf("output package name='%s' differs from the package='%s' of the directory='%s'",
+				s.output.Name, outPkg.Name, dir)
+		}
+
+		outName = s.output.Name
+	}
+
+	if outTypes.Path() == typePkg.Types.Path() {
+		return nil, fmt.Errorf("type='%s' is declared by the package the builder is generated into, "+
+			"annotate it instead", typeName)
+	}
+
+	named, st, err := lookupStructType(typePkg.Types, name)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := &typeResolver{
+		fset:     typePkg.Fset,
+		pkg:      outTypes,
+		target:   outTypes,
+		info:     nil,
+		imports:  nil,
+		used:     nil,
+		extra:    make([]fileImport, 0),
+		builders: nil,
+	}
+
+	fields := make([]model.Field, 0, st.NumFields())
+
+	for i := 0; i < st.NumFields(); i++ {
+		// fields of unexported types can't be set outside the package as well as unexported fields,
+		// and the ones containing locks can't be passed by value
+		f := st.Field(i)
+		if !f.Exported() || findUnexportedType(typePkg.Types, f.Type()) != "" || hasLock(f.Type()) {
+			continue
+		}
+
+		fields = append(fields, model.Field{
+			Name:        f.Name(),
+			Type:        resolver.fieldTypeOf(f.Type()),
+			Private:     false,
+			Required:    false,
+			Default:     "",
+			Validations: nil,
+			Nested:      nil,
+			Alias:       "",
+			Readonly:    false,
+		})
+	}
+
+	// the files of the output package are only parsed unless it's loaded, e.g. for the external test package
+	var decls []string
+	if outPkg != nil && outTypes == outPkg.Types && outName == outPkg.Name {
+		decls = getPackageDecls(outPkg, findGeneratedFiles(outPkg))
+	} else if decls, err = getDirDecls(dir, outName); err != nil {
+		return nil, err
+	}
+
+	structs := []model.Struct{
+		{
+			Name:       name,
+			Private:    false,
+			Qualifier:  resolver.qualifier(named.Obj().Pkg()),
+			TypeParams: nil,
+			Directive:  nil,
+			Methods:    nil,
+			NoCopy:     hasLock(named),
+			Fields:     fields,
+		},
+	}
+
+	return &model.File{
+		Name:    typeName,
+		Path:    dir,
+		Pkg:     outName,
+		Imports: resolver.usedImports(),
+		Structs: structs,
+		Others:  nil,
+		Decls:   decls,
+	}, nil
+}
+
+// splitTypeName splits the qualified type name into the import path and the name of the type,
+// e.g. net/http.Server results in net/http and Server.
+func splitTypeName(typeName string) (string, string, error) {
+	idx := strings.LastIndex(typeName, ".")
+	if idx <= 0 || idx < strings.LastIndex(typeName, "/") {
+		return "", "", fmt.Errorf("type='%s' must be qualified by the import path, e.g. net/http.Server", typeName)
+	}
+
+	importPath, name := typeName[:idx], typeName[idx+1:]
+	if !gotoken.IsIdentifier(name) {
+		return "", "", fmt.Errorf("type name='%s' is not an identifier", name)
+	}
+
+	return importPath, name, nil
+}
+
+// findTypePackages returns the package of the current directory along with the package declaring the type.
+func findTypePackages(pkgs []*packages.Package, importPath string) (*packages.Package, *packages.Package) {
+	var outPkg, typePkg *packages.Package
+
+	for _, pkg := range pkgs {
+		if pkg.PkgPath == importPath {
+			typePkg = pkg
+		} else {
+			outPkg = pkg
+		}
+	}
+
+	return outPkg, typePkg
+}
+
+// getOutputPackage returns the name and the types of the package the builder is generated into,
+// which is named after the directory if it doesn't exist or has no Go files yet.
+func getOutputPackage(pkg *packages.Package, dir string) (string, *gotypes.Package) {
+	if pkg == nil || pkg.Types == nil || pkg.Name == "" {
+		return filepath.Base(dir), gotypes.NewPackage(dir, filepath.Base(dir))
+	}
+
+	return pkg.Name, pkg.Types
+}
+
+// checkTypePackageErrors reports the errors of the package declaring the type, unlike the annotated sources
+// it's expected to be type-checked successfully.
+func checkTypePackageErrors(pkg *packages.Package) error {
+	if len(pkg.Errors) > 0 {
+		return pkg.Errors[0]
+	}
+
+	if pkg.Types == nil {
+		return fmt.Errorf("no type information of the package='%s'", pkg.PkgPath)
+	}
+
+	return nil
+}
+
+func lookupStructType(pkg *gotypes.Package, name string) (*gotypes.Named, *gotypes.Struct, error) {
+	obj, ok := pkg.Scope().Lookup(name).(*gotypes.TypeName)
+	if !ok {
+		return nil, nil, fmt.Errorf("type='%s' not found in the package='%s'", name, pkg.Path())
+	}
+
+	if !obj.Exported() {
+		return nil, nil, fmt.Errorf("type='%s' of the package='%s' is not exported", name, pkg.Path())
+	}
+
+	named, ok := gotypes.Unalias(obj.Type()).(*gotypes.Named)
+	if !ok {
+		return nil, nil, fmt.Errorf("type='%s' of the package='%s' is not a struct", name, pkg.Path())
+	}
+
+	st, ok := named.Underlying().(*gotypes.Struct)
+	if !ok {
+		return nil, nil, fmt.Errorf("type='%s' of the package='%s' is not a struct", name, pkg.Path())
+	}
+
+	if named.Obj().Pkg() != pkg || named.Obj().Name() != name {
+		return nil, nil, fmt.Errorf("type='%s' of the package='%s' is an alias of the type='%s'", name, pkg.Path(), named)
+	}
+
+	if named.TypeParams().Len() > 0 {
+		return nil, nil, fmt.Errorf("generic type='%s' of the package='%s' is not supported", name, pkg.Path())
+	}
+
+	return named, st, nil
+}
@@ -0,0 +1,751 @@
+package service
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	goparser "go/parser"
+	gotoken "go/token"
+	gotypes "go/types"
+	"math"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/slavaavr/go-struct-builder/internal/model"
+)
+
+const (
+	moPkgPath    = "github.com/samber/mo"
+	moOptionType = "Option"
+)
+
+type fileImport struct {
+	path  string
+	name  string
+	value model.Import
+}
+
+// typeResolver maps field types of a type-checked file to the model
+// and keeps track of the imports the resolved types refer to.
+type typeResolver struct {
+	fset *gotoken.FileSet
+	pkg  *gotypes.Package
+	// target is the package the builders are generated into, the types are qualified relative to it
+	target  *gotypes.Package
+	info    *gotypes.Info
+	imports []fileImport
+	used    []bool
+	// extra are the imports of the packages the file doesn't import, which the resolved types refer to
+	extra []fileImport
+	// builders are the structs of the package the builders are generated for
+	builders map[*gotypes.TypeName]builderDecl
+}
+
+func newTypeResolver(
+	fset *gotoken.FileSet,
+	pkg *gotypes.Package,
+	info *gotypes.Info,
+	file *ast.File,
+	builders map[*gotypes.TypeName]builderDecl,
+) *typeResolver {
+	imports := make([]fileImport, 0, len(file.Imports))
+
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		var (
+			alias *string
+			name  string
+		)
+
+		switch {
+		case spec.Name != nil:
+			alias = &spec.Name.Name
+			name = spec.Name.Name
+
+		case info.PkgNameOf(spec) != nil:
+			name = info.PkgNameOf(spec).Imported().Name()
+
+		default:
+			name = path.Base(importPath)
+		}
+
+		imports = append(imports, fileImport{
+			path: importPath,
+			name: name,
+			value: model.Import{
+				Value: spec.Path.Value,
+				Alias: alias,
+			},
+		})
+	}
+
+	return &typeResolver{
+		fset:     fset,
+		pkg:      pkg,
+		target:   pkg,
+		info:     info,
+		imports:  imports,
+		used:     make([]bool, len(imports)),
+		extra:    make([]fileImport, 0),
+		builders: builders,
+	}
+}
+
+func (r *typeResolver) fieldType(expr ast.Expr) model.FieldType {
+	typ := r.info.TypeOf(expr)
+	if typ == nil || !isValidType(typ) {
+		// the type refers to a package that could not be loaded
+		return r.fieldTypeFromExpr(expr)
+	}
+
+	return r.fieldTypeOf(typ)
+}
+
+// fieldTypeOf maps the type of a field to the model, it's used directly for the fields of the structs
+// declared by other packages.
+func (r *typeResolver) fieldTypeOf(typ gotypes.Type) model.FieldType {
+	res := model.FieldType{
+		Name:   r.typeString(typ),
+		Elem:   "",
+		Key:    "",
+		Info:   model.TypeInfoOther,
+		Kind:   getTypeKind(typ),
+		NoCopy: false,
+	}
+
+	if elem, ok := r.optionElem(typ); ok {
+		res.Info = model.TypeInfoOption
+		res.Elem = r.typeString(elem)
+		// the same kind as when the mo package could not be loaded
+		res.Kind = model.TypeKindOther
+
+		return res
+	}
+
+	switch t := typ.Underlying().(type) {
+	case *gotypes.Pointer:
+		res.Info = model.TypeInfoPointer
+		res.Elem = r.typeString(t.Elem())
+		res.NoCopy = hasLock(t.Elem())
+
+	case *gotypes.Slice:
+		res.Info = model.TypeInfoArray
+		res.Elem = r.typeString(t.Elem())
+
+	case *gotypes.Map:
+		res.Info = model.TypeInfoMap
+		res.Key = r.typeString(t.Key())
+		res.Elem = r.typeString(t.Elem())
+	}
+
+	return res
+}
+
+// nested returns the builder of the struct the field type refers to either directly or by a pointer.
+func (r *typeResolver) nested(expr ast.Expr) *model.Nested {
+	typ := r.info.TypeOf(expr)
+	if typ == nil || !isValidType(typ) {
+		return nil
+	}
+
+	if ptr, ok := typ.(*gotypes.Pointer); ok {
+		typ = ptr.Elem()
+	}
+
+	named, ok := gotypes.Unalias(typ).(*gotypes.Named)
+	if !ok || named.TypeArgs().Len() > 0 {
+		return nil
+	}
+
+	decl, ok := r.builders[named.Obj()]
+	if !ok {
+		return nil
+	}
+
+	return &model.Nested{
+		Struct:    named.Obj().Name(),
+		Private:   !named.Obj().Exported(),
+		Required:  false,
+		Validated: false,
+		Nested:    nil,
+		Directive: decl.spec.directive,
+	}
+}
+
+func (r *typeResolver) typeName(expr ast.Expr) string {
+	typ := r.info.TypeOf(expr)
+	if typ == nil || !isValidType(typ) {
+		r.markExprImports(expr)
+
+		return gotypes.ExprString(expr)
+	}
+
+	return r.typeString(typ)
+}
+
+// checkDefault validates a default field value: it must be a constant or a function call without arguments
+// assignable to the field type.
+func (r *typeResolver) checkDefault(value string, fieldType ast.Expr) error {
+	expr, err := goparser.ParseExpr(value)
+	if err != nil {
+		return fmt.Errorf("parsing default value='%s': %w", value, err)
+	}
+
+	call, isCall := expr.(*ast.CallExpr)
+	if isCall && len(call.Args) > 0 {
+		return fmt.Errorf("default value='%s' must be a function call without arguments", value)
+	}
+
+	r.markExprImports(expr)
+
+	typ := r.info.TypeOf(fieldType)
+	if typ == nil || !isValidType(typ) {
+		// the field type refers to a package that could not be loaded
+		return nil
+	}
+
+	tv, err := gotypes.Eval(r.fset, r.pkg, fieldType.Pos(), value)
+	if err != nil {
+		return fmt.Errorf("evaluating default value='%s': %w", value, err)
+	}
+
+	if !isCall && tv.Value == nil {
+		return fmt.Errorf("default value='%s' must be a constant or a function call", value)
+	}
+
+	if !gotypes.AssignableTo(tv.Type, typ) {
+		return fmt.Errorf("default value='%s' of type %s is not assignable to %s", value, tv.Type, typ)
+	}
+
+	return nil
+}
+
+// isRepresentable reports whether the numeric value of a validation rule can be represented by the field type,
+// e.g. 300 overflows uint8. The values compared with the fields of unresolved types are left to the compiler.
+func (r *typeResolver) isRepresentable(value string, fieldType ast.Expr) bool {
+	typ := r.info.TypeOf(fieldType)
+	if typ == nil || !isValidType(typ) {
+		return true
+	}
+
+	basic, ok := typ.Underlying().(*gotypes.Basic)
+	if !ok {
+		return true
+	}
+
+	tv, err := gotypes.Eval(r.fset, nil, gotoken.NoPos, value)
+	if err != nil || tv.Value == nil {
+		return false
+	}
+
+	return isConstantRepresentable(tv.Value, basic)
+}
+
+// isConstantRepresentable reports whether the constant fits the basic type by the sizes of the gc compiler.
+func isConstantRepresentable(value constant.Value, basic *gotypes.Basic) bool {
+	switch {
+	case basic.Info()&gotypes.IsInteger != 0:
+		value = constant.ToInt(value)
+		if value.Kind() != constant.Int {
+			return false
+		}
+
+		var (
+			bits = uint(gotypes.SizesFor("gc", runtime.GOARCH).Sizeof(basic)) * bitsInByte
+			one  = constant.MakeInt64(1)
+			low  = constant.MakeInt64(0)
+			high = constant.Shift(one, gotoken.SHL, bits)
+		)
+
+		if basic.Info()&gotypes.IsUnsigned == 0 {
+			high = constant.Shift(one, gotoken.SHL, bits-1)
+			low = constant.UnaryOp(gotoken.SUB, high, 0)
+		}
+
+		return constant.Compare(value, gotoken.GEQ, low) && constant.Compare(value, gotoken.LSS, high)
+
+	case basic.Kind() == gotypes.Float32:
+		f, _ := constant.Float32Val(constant.ToFloat(value))
+
+		return !math.IsInf(float64(f), 0)
+
+	case basic.Kind() == gotypes.Float64:
+		f, _ := constant.Float64Val(constant.ToFloat(value))
+
+		return !math.IsInf(f, 0)
+
+	default:
+		return true
+	}
+}
+
+func (r *typeResolver) fieldTypeFromExpr(expr ast.Expr) model.FieldType {
+	r.markExprImports(expr)
+
+	res := model.FieldType{
+		Name:   gotypes.ExprString(expr),
+		Elem:   "",
+		Key:    "",
+		Info:   model.TypeInfoOther,
+		Kind:   model.TypeKindOther,
+		NoCopy: false,
+	}
+
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		res.Info = model.TypeInfoPointer
+		res.Elem = gotypes.ExprString(e.X)
+		res.Kind = model.TypeKindNillable
+
+	case *ast.ArrayType:
+		res.Kind = model.TypeKindArray
+
+		if e.Len == nil {
+			res.Info = model.TypeInfoArray
+			res.Elem = gotypes.ExprString(e.Elt)
+			res.Kind = model.TypeKindSlice
+		}
+
+	case *ast.MapType:
+		res.Info = model.TypeInfoMap
+		res.Key = gotypes.ExprString(e.Key)
+		res.Elem = gotypes.ExprString(e.Value)
+		res.Kind = model.TypeKindMap
+
+	case *ast.IndexExpr:
+		if r.isOptionExpr(e.X) {
+			res.Info = model.TypeInfoOption
+			res.Elem = gotypes.ExprString(e.Index)
+		}
+	}
+
+	return res
+}
+
+func (r *typeResolver) optionElem(typ gotypes.Type) (gotypes.Type, bool) {
+	named, ok := gotypes.Unalias(typ).(*gotypes.Named)
+	if !ok {
+		return nil, false
+	}
+
+	obj := named.Origin().Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path() != moPkgPath || obj.Name() != moOptionType {
+		return nil, false
+	}
+
+	if named.TypeArgs().Len() != 1 {
+		return nil, false
+	}
+
+	return named.TypeArgs().At(0), true
+}
+
+func (r *typeResolver) isOptionExpr(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	idx := r.importIndex(ident)
+
+	return idx != -1 && r.imports[idx].path == moPkgPath && sel.Sel.Name == moOptionType
+}
+
+func (r *typeResolver) markExprImports(expr ast.Expr) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		if ident, ok := sel.X.(*ast.Ident); ok {
+			if idx := r.importIndex(ident); idx != -1 {
+				r.used[idx] = true
+			}
+		}
+
+		return true
+	})
+}
+
+func (r *typeResolver) importIndex(ident *ast.Ident) int {
+	for i, imp := range r.imports {
+		if imp.name == ident.Name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (r *typeResolver) typeString(typ gotypes.Type) string {
+	return gotypes.TypeString(typ, r.qualifier)
+}
+
+func (r *typeResolver) qualifier(pkg *gotypes.Package) string {
+	if pkg == r.target {
+		return ""
+	}
+
+	for i, imp := range r.imports {
+		if imp.path != pkg.Path() || imp.name == "_" {
+			continue
+		}
+
+		r.used[i] = true
+
+		if imp.name == "." {
+			return ""
+		}
+
+		return imp.name
+	}
+
+	// the package is referenced only indirectly, e.g. through a type declared in another file
+	for _, imp := range r.extra {
+		if imp.path == pkg.Path() {
+			return imp.name
+		}
+	}
+
+	var (
+		name  = r.importName(pkg.Name())
+		alias *string
+	)
+
+	if name != pkg.Name() {
+		alias = &name
+	}
+
+	r.extra = append(r.extra, fileImport{
+		path: pkg.Path(),
+		name: name,
+		value: model.Import{
+			Value: strconv.Quote(pkg.Path()),
+			Alias: alias,
+		},
+	})
+
+	return name
+}
+
+// importName returns the name of an extra import of the package, the package name suffixed by a number
+// if it's already taken by another import or a declaration of the target package, e.g. rand2 for
+// math/rand imported along with crypto/rand.
+func (r *typeResolver) importName(pkgName string) string {
+	name := pkgName
+
+	for i := 2; r.isNameTaken(name); i++ {
+		name = pkgName + strconv.Itoa(i)
+	}
+
+	return name
+}
+
+func (r *typeResolver) isNameTaken(name string) bool {
+	for _, imps := range [][]fileImport{r.imports, r.extra} {
+		for _, imp := range imps {
+			if imp.name == name {
+				return true
+			}
+		}
+	}
+
+	return r.target != nil && r.target.Scope().Lookup(name) != nil
+}
+
+func (r *typeResolver) usedImports() []model.Import {
+	res := make([]model.Import, 0, len(r.imports)+len(r.extra))
+
+	for i, imp := range r.imports {
+		if r.used[i] {
+			res = append(res, imp.value)
+		}
+	}
+
+	for _, imp := range r.extra {
+		res = append(res, imp.value)
+	}
+
+	return res
+}
+
+func getTypeKind(typ gotypes.Type) model.TypeKind {
+	switch t := typ.Underlying().(type) {
+	case *gotypes.Basic:
+		switch {
+		case t.Info()&gotypes.IsBoolean != 0:
+			return model.TypeKindBool
+
+		case t.Info()&gotypes.IsInteger != 0:
+			return model.TypeKindInt
+
+		case t.Info()&gotypes.IsFloat != 0:
+			return model.TypeKindFloat
+
+		case t.Info()&gotypes.IsString != 0:
+			return model.TypeKindString
+		}
+
+	case *gotypes.Slice:
+		return model.TypeKindSlice
+
+	case *gotypes.Map:
+		return model.TypeKindMap
+
+	case *gotypes.Array:
+		return model.TypeKindArray
+
+	case *gotypes.Struct:
+		return model.TypeKindStruct
+
+	case *gotypes.Pointer, *gotypes.Chan, *gotypes.Signature:
+		return model.TypeKindNillable
+
+	case *gotypes.Interface:
+		if _, ok := typ.(*gotypes.TypeParam); !ok {
+			return model.TypeKindNillable
+		}
+	}
+
+	return model.TypeKindOther
+}
+
+// isComparable reports whether values of the type can be compared with ==.
+func (r *typeResolver) isComparable(expr ast.Expr) bool {
+	typ := r.info.TypeOf(expr)
+
+	return typ != nil && isValidType(typ) && gotypes.Comparable(typ)
+}
+
+func isValidType(typ gotypes.Type) bool {
+	switch t := typ.(type) {
+	case *gotypes.Basic:
+		return t.Kind() != gotypes.Invalid
+
+	case *gotypes.Alias:
+		return isValidType(gotypes.Unalias(t))
+
+	case *gotypes.Pointer:
+		return isValidType(t.Elem())
+
+	case *gotypes.Slice:
+		return isValidType(t.Elem())
+
+	case *gotypes.Array:
+		return isValidType(t.Elem())
+
+	case *gotypes.Map:
+		return isValidType(t.Key()) && isValidType(t.Elem())
+
+	case *gotypes.Chan:
+		return isValidType(t.Elem())
+
+	case *gotypes.Named:
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if !isValidType(t.TypeArgs().At(i)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// lockerType is the interface of sync.Locker, the types implementing it by a pointer are locks,
+// which go vet reports being copied by value.
+var lockerType = gotypes.NewInterfaceType([]*gotypes.Func{
+	gotypes.NewFunc(gotoken.NoPos, nil, "Lock", gotypes.NewSignatureType(nil, nil, nil, nil, nil, false)),
+	gotypes.NewFunc(gotoken.NoPos, nil, "Unlock", gotypes.NewSignatureType(nil, nil, nil, nil, nil, false)),
+}, nil).Complete()
+
+// noCopy reports whether the values of the type declared by the name contain a lock.
+func (r *typeResolver) noCopy(name *ast.Ident) bool {
+	obj := r.info.Defs[name]
+
+	return obj != nil && hasLock(obj.Type())
+}
+
+// hasLock reports whether a value of the type contains a lock, e.g. a sync.Mutex or a noCopy field
+// of the struct or of the structs it embeds by value.
+func hasLock(typ gotypes.Type) bool {
+	if _, ok := typ.(*gotypes.TypeParam); ok || !isValidType(typ) {
+		return false
+	}
+
+	if gotypes.Implements(gotypes.NewPointer(typ), lockerType) && !gotypes.Implements(typ, lockerType) {
+		return true
+	}
+
+	switch t := typ.Underlying().(type) {
+	case *gotypes.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if hasLock(t.Field(i).Type()) {
+				return true
+			}
+		}
+
+	case *gotypes.Array:
+		return hasLock(t.Elem())
+	}
+
+	return false
+}
+
+// isOutside reports whether the builders are generated outside the package of the sources.
+func (r *typeResolver) isOutside() bool {
+	return r.target != r.pkg
+}
+
+// structQualifier returns the name the package of the sources is imported by the generated code,
+// empty if the builders are generated into the package of the sources.
+func (r *typeResolver) structQualifier() string {
+	if !r.isOutside() {
+		return ""
+	}
+
+	return r.qualifier(r.pkg)
+}
+
+// qualifyExpr qualifies the identifiers of the expression declared by the package of the sources,
+// so the expression can be used outside the package, e.g. defaultPort() results in config.defaultPort().
+func (r *typeResolver) qualifyExpr(value string) (string, error) {
+	if !r.isOutside() {
+		return value, nil
+	}
+
+	expr, err := goparser.ParseExpr(value)
+	if err != nil {
+		return "", fmt.Errorf("parsing expression='%s': %w", value, err)
+	}
+
+	idents := make([]*ast.Ident, 0)
+
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.SelectorExpr:
+			// the selected name belongs to the operand, e.g. a package or a type
+			ast.Inspect(e.X, func(n ast.Node) bool {
+				if ident, ok := n.(*ast.Ident); ok {
+					idents = append(idents, ident)
+				}
+
+				return true
+			})
+
+			return false
+
+		case *ast.Ident:
+			idents = append(idents, e)
+		}
+
+		return true
+	})
+
+	var (
+		res    strings.Builder
+		offset int
+	)
+
+	for _, ident := range idents {
+		obj := r.pkg.Scope().Lookup(ident.Name)
+		if obj == nil {
+			continue
+		}
+
+		if !obj.Exported() {
+			return "", fmt.Errorf("expression='%s' refers to unexported '%s' of the package='%s'",
+				value, ident.Name, r.pkg.Path())
+		}
+
+		// positions of the parsed expression start with 1
+		pos := int(ident.Pos()) - 1
+		res.WriteString(value[offset:pos])
+		res.WriteString(r.qualifier(r.pkg) + ".")
+		offset = pos
+	}
+
+	res.WriteString(value[offset:])
+
+	return res.String(), nil
+}
+
+// unexportedType returns the name of the unexported type of the package of the sources
+// the field type refers to, which can't be used outside the package.
+func (r *typeResolver) unexportedType(expr ast.Expr) string {
+	typ := r.info.TypeOf(expr)
+	if typ == nil {
+		return ""
+	}
+
+	return findUnexportedType(r.pkg, typ)
+}
+
+// findUnexportedType returns the name of the unexported type of the pkg the type refers to.
+func findUnexportedType(pkg *gotypes.Package, typ gotypes.Type) string {
+	switch t := typ.(type) {
+	case *gotypes.Alias:
+		if t.Obj().Pkg() == pkg && !t.Obj().Exported() {
+			return t.Obj().Name()
+		}
+
+		return findUnexportedType(pkg, gotypes.Unalias(t))
+
+	case *gotypes.Named:
+		if t.Obj().Pkg() == pkg && !t.Obj().Exported() {
+			return t.Obj().Name()
+		}
+
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if name := findUnexportedType(pkg, t.TypeArgs().At(i)); name != "" {
+				return name
+			}
+		}
+
+	case *gotypes.Pointer:
+		return findUnexportedType(pkg, t.Elem())
+
+	case *gotypes.Slice:
+		return findUnexportedType(pkg, t.Elem())
+
+	case *gotypes.Array:
+		return findUnexportedType(pkg, t.Elem())
+
+	case *gotypes.Chan:
+		return findUnexportedType(pkg, t.Elem())
+
+	case *gotypes.Map:
+		if name := findUnexportedType(pkg, t.Key()); name != "" {
+			return name
+		}
+
+		return findUnexportedType(pkg, t.Elem())
+
+	case *gotypes.Signature:
+		for _, tuple := range []*gotypes.Tuple{t.Params(), t.Results()} {
+			for i := 0; i < tuple.Len(); i++ {
+				if name := findUnexportedType(pkg, tuple.At(i).Type()); name != "" {
+					return name
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// position returns the position in the source file, e.g. input.go:5:2 with the full file path.
+func (r *typeResolver) position(pos gotoken.Pos) gotoken.Position {
+	return r.fset.Position(pos)
+}